@@ -1,17 +1,68 @@
 package root
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+
 	"github.com/nguyendangminh/gus/pkg/core"
+	"github.com/nguyendangminh/gus/pkg/formatter"
+	"github.com/nguyendangminh/gus/pkg/git"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// jsonOutput determines if the output should be in JSON format
 	jsonOutput bool
+	// format is the name of the output format
+	format string
+	// templateText is the Go template the output is rendered with
+	templateText string
+	// templateFile is a file containing the Go template
+	templateFile string
+	// colorMode tells when output is colored
+	colorMode string
+	// group writes one row per repository in csv and tsv output
+	group bool
+	// metricsRepoLabel tells how prometheus output labels repositories
+	metricsRepoLabel string
+	// metricsMaxRepos limits the repositories with metrics of their own
+	metricsMaxRepos int
 	// rootPath is the path to scan for Git repositories
 	rootPath string
 	// verbose determines if verbose output should be shown
 	verbose bool
+	// jobs is the number of repositories checked in parallel
+	jobs int
+	// excludes are glob patterns of directories to skip
+	excludes []string
+	// noDefaultExcludes disables the built-in exclude patterns
+	noDefaultExcludes bool
+	// maxDepth limits how deep the scan descends
+	maxDepth int
+	// followSymlinks determines if symbolic links to directories are followed
+	followSymlinks bool
+	// oneFileSystem keeps the scan on the file system of the scanned path
+	oneFileSystem bool
+	// unpushed determines if clean repositories with unpushed commits are reported
+	unpushed bool
+	// stashes determines if repositories with stashed changes are treated as dirty
+	stashes bool
+	// states restricts the results to repositories in these states
+	states []string
+	// exitZero determines if dirty repositories and partial errors still exit with 0
+	exitZero bool
+	// timeout limits how long the status check of a single repository may take
+	timeout time.Duration
+	// deadline limits how long the whole scan may take
+	deadline time.Duration
+
+	// exitCode is the exit code of the last run
+	exitCode int
 )
 
 // NewRootCmd creates the root command
@@ -22,31 +73,170 @@ func NewRootCmd() *cobra.Command {
 		Long: `A command-line tool to scan directories for Git repositories with uncommitted changes.
 It recursively searches through directories to find Git repositories and checks their status.`,
 		RunE: run,
+		// Errors are printed by Execute
+		SilenceErrors: true,
 	}
 
 	// Add flags
-	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output in JSON format")
+	cmd.Flags().StringVar(&format, "format", formatter.DefaultFormat, "output format ("+strings.Join(formatter.Names(), ", ")+")")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output in JSON format (same as --format json)")
+	cmd.Flags().StringVar(&templateText, "template", "", "render the output with a Go template (same as --format template)")
+	cmd.Flags().StringVar(&templateFile, "template-file", "", "render the output with the Go template in a file")
+	cmd.Flags().BoolVar(&group, "group", false, "write one row per repository instead of one per changed file (csv and tsv)")
+	cmd.Flags().StringVar(&metricsRepoLabel, "metrics-repo-label", string(formatter.RepoLabelPath), "how repositories are labelled in prometheus output: path, relpath or none")
+	cmd.Flags().IntVar(&metricsMaxRepos, "metrics-max-repos", 0, "only write per-repository metrics for the N repositories with the most changes (0 means no limit)")
+	cmd.Flags().StringVar(&colorMode, "color", string(formatter.ColorAuto), "color output: auto, always or never (auto respects NO_COLOR)")
 	cmd.Flags().StringVar(&rootPath, "path", ".", "path to scan for Git repositories")
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	cmd.Flags().IntVar(&jobs, "jobs", runtime.NumCPU(), "number of repositories to check in parallel")
+	cmd.Flags().StringSliceVar(&excludes, "exclude", nil, "glob pattern of directories to skip (repeatable)")
+	cmd.Flags().BoolVar(&noDefaultExcludes, "no-default-excludes", false, "do not skip node_modules, vendor and other well-known directories")
+	cmd.Flags().IntVar(&maxDepth, "max-depth", 0, "maximum directory depth to descend into (0 means no limit)")
+	cmd.Flags().BoolVar(&followSymlinks, "follow-symlinks", false, "follow symbolic links to directories")
+	cmd.Flags().BoolVar(&oneFileSystem, "one-file-system", false, "do not cross into other file systems")
+	cmd.Flags().BoolVar(&unpushed, "unpushed", false, "also report clean repositories with commits not pushed to any remote")
+	cmd.Flags().BoolVar(&stashes, "stashes", false, "treat repositories with stashed changes as dirty")
+	cmd.Flags().BoolVar(&exitZero, "exit-zero", false, "exit with 0 even if repositories are reported or could not be checked")
+	cmd.Flags().StringSliceVar(&states, "state", nil, "only report repositories in these states (merging, rebasing, applying, cherry-picking, reverting, bisecting, detached)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "maximum time to check a single repository, e.g. 10s (0 means no limit)")
+	cmd.Flags().DurationVar(&deadline, "deadline", 0, "maximum time for the whole scan, after which partial results are printed (0 means no limit)")
 
 	return cmd
 }
 
+// Execute runs the root command with the command line arguments and returns
+// the process exit code:
+//
+//	0  no repositories with uncommitted changes were found
+//	1  repositories with uncommitted changes were found
+//	2  some repositories could not be checked, or the scan was interrupted
+//	3  the scan failed
+//
+// With --exit-zero, 1 and 2 become 0.
+func Execute() int {
+	return execute(os.Args[1:])
+}
+
+// execute runs the root command with the given arguments
+func execute(args []string) int {
+	cmd := NewRootCmd()
+	cmd.SetArgs(args)
+
+	exitCode = core.ExitClean
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return core.ExitFatal
+	}
+	return exitCode
+}
+
 // run is the main function that will be executed when the command is run
 func run(cmd *cobra.Command, args []string) error {
+	// The flags parsed fine, so usage would not help with later errors
+	cmd.SilenceUsage = true
+
 	// If path is provided as an argument, override the flag
 	if len(args) > 0 {
 		rootPath = args[0]
 	}
 
+	if err := resolveFormat(cmd); err != nil {
+		return err
+	}
+	color, err := formatter.ParseColorMode(colorMode)
+	if err != nil {
+		return err
+	}
+	repoLabel, err := formatter.ParseRepoLabel(metricsRepoLabel)
+	if err != nil {
+		return err
+	}
+
+	var repoStates []git.State
+	for _, name := range states {
+		state, err := git.ParseState(name)
+		if err != nil {
+			return err
+		}
+		repoStates = append(repoStates, state)
+	}
+
 	// Create scanner with options
 	options := core.Options{
-		Path:    rootPath,
-		JSON:    jsonOutput,
-		Verbose: verbose,
+		Path:              rootPath,
+		Format:            format,
+		Template:          templateText,
+		Color:             color,
+		Group:             group,
+		MetricsRepoLabel:  repoLabel,
+		MetricsMaxRepos:   metricsMaxRepos,
+		Verbose:           verbose,
+		Jobs:              jobs,
+		Excludes:          excludes,
+		NoDefaultExcludes: noDefaultExcludes,
+		MaxDepth:          maxDepth,
+		FollowSymlinks:    followSymlinks,
+		OneFileSystem:     oneFileSystem,
+		Unpushed:          unpushed,
+		Stashes:           stashes,
+		States:            repoStates,
+		Timeout:           timeout,
+		Deadline:          deadline,
 	}
 	scanner := core.New(options)
 
+	// The first Ctrl-C stops the scan and prints the partial results; a
+	// second one kills the process as usual
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	// Run the scanner
-	return scanner.Run()
+	code, err := scanner.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	exitCode = code
+	if exitZero {
+		exitCode = core.ExitClean
+	}
+	return nil
+}
+
+// resolveFormat checks the output flags and settles format and templateText,
+// so that mistakes are reported before the scan starts
+func resolveFormat(cmd *cobra.Command) error {
+	if jsonOutput {
+		if cmd.Flags().Changed("format") && format != "json" {
+			return fmt.Errorf("--json conflicts with --format %s", format)
+		}
+		format = "json"
+	}
+	if templateFile != "" {
+		if templateText != "" {
+			return fmt.Errorf("--template conflicts with --template-file")
+		}
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return fmt.Errorf("reading template: %w", err)
+		}
+		templateText = string(data)
+	}
+	if templateText != "" {
+		if (cmd.Flags().Changed("format") || jsonOutput) && format != "template" {
+			return fmt.Errorf("a template conflicts with --format %s", format)
+		}
+		format = "template"
+		if _, err := formatter.ParseTemplate(templateText); err != nil {
+			return err
+		}
+	} else if format == "template" {
+		return fmt.Errorf("--format template requires --template or --template-file")
+	}
+	_, err := formatter.Lookup(format)
+	return err
 }
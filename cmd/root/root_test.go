@@ -2,7 +2,13 @@ package root
 
 import (
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"testing"
+
+	"github.com/nguyendangminh/gus/pkg/core"
 )
 
 func TestNewRootCmd(t *testing.T) {
@@ -25,6 +31,14 @@ func TestNewRootCmd(t *testing.T) {
 		t.Error("Expected 'json' flag to default to false")
 	}
 
+	formatFlag := cmd.Flags().Lookup("format")
+	if formatFlag == nil {
+		t.Fatal("Expected 'format' flag to be defined")
+	}
+	if formatFlag.Value.String() != "text" {
+		t.Errorf("Expected 'format' flag to default to 'text', got '%s'", formatFlag.Value.String())
+	}
+
 	pathFlag := cmd.Flags().Lookup("path")
 	if pathFlag == nil {
 		t.Error("Expected 'path' flag to be defined")
@@ -40,6 +54,14 @@ func TestNewRootCmd(t *testing.T) {
 	if verboseFlag.Value.String() != "false" {
 		t.Error("Expected 'verbose' flag to default to false")
 	}
+
+	jobsFlag := cmd.Flags().Lookup("jobs")
+	if jobsFlag == nil {
+		t.Fatal("Expected 'jobs' flag to be defined")
+	}
+	if jobsFlag.Value.String() != strconv.Itoa(runtime.NumCPU()) {
+		t.Errorf("Expected 'jobs' flag to default to %d, got %s", runtime.NumCPU(), jobsFlag.Value.String())
+	}
 }
 
 func TestRun(t *testing.T) {
@@ -84,7 +106,24 @@ func TestRun(t *testing.T) {
 		t.Error("Expected error for invalid path")
 	}
 
-	// Test case 5: Path as argument
+	// Test case 5: Invalid state filter
+	cmd = NewRootCmd()
+	rootPath = tempDir
+	states = []string{"sleeping"}
+	err = run(cmd, nil)
+	if err == nil {
+		t.Error("Expected error for invalid state")
+	}
+	states = nil
+
+	// Test case 6: Usage is only printed for flag errors
+	cmd = NewRootCmd()
+	rootPath = "/invalid/path"
+	if err := run(cmd, nil); err == nil || !cmd.SilenceUsage {
+		t.Errorf("Expected usage to be silenced for run errors, got %v", err)
+	}
+
+	// Test case 7: Path as argument
 	cmd = NewRootCmd()
 	rootPath = "."
 	err = run(cmd, []string{tempDir})
@@ -92,3 +131,98 @@ func TestRun(t *testing.T) {
 		t.Errorf("Run failed with path argument: %v", err)
 	}
 }
+
+func TestExecuteExitCodes(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "root-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Test case 1: No repositories
+	if code := execute([]string{tempDir}); code != core.ExitClean {
+		t.Errorf("Expected exit code %d for an empty directory, got %d", core.ExitClean, code)
+	}
+
+	// Test case 2: Clean repository
+	repoDir := filepath.Join(tempDir, "repo")
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repoDir
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to initialize Git repository: %v", err)
+	}
+	if code := execute([]string{tempDir}); code != core.ExitClean {
+		t.Errorf("Expected exit code %d for a clean repository, got %d", core.ExitClean, code)
+	}
+
+	// Test case 3: Dirty repository
+	if err := os.WriteFile(filepath.Join(repoDir, "test.txt"), []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if code := execute([]string{tempDir}); code != core.ExitDirty {
+		t.Errorf("Expected exit code %d for a dirty repository, got %d", core.ExitDirty, code)
+	}
+	if code := execute([]string{"--exit-zero", tempDir}); code != core.ExitClean {
+		t.Errorf("Expected exit code %d with --exit-zero, got %d", core.ExitClean, code)
+	}
+
+	// Test case 4: Repository that cannot be checked
+	if err := os.MkdirAll(filepath.Join(tempDir, "broken", ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if code := execute([]string{tempDir}); code != core.ExitPartial {
+		t.Errorf("Expected exit code %d for partial errors, got %d", core.ExitPartial, code)
+	}
+	if code := execute([]string{"--exit-zero", tempDir}); code != core.ExitClean {
+		t.Errorf("Expected exit code %d with --exit-zero, got %d", core.ExitClean, code)
+	}
+
+	// Test case 5: Fatal errors are not overridden
+	if code := execute([]string{"--exit-zero", "/invalid/path"}); code != core.ExitFatal {
+		t.Errorf("Expected exit code %d for an invalid path, got %d", core.ExitFatal, code)
+	}
+	if code := execute([]string{"--no-such-flag"}); code != core.ExitFatal {
+		t.Errorf("Expected exit code %d for an unknown flag, got %d", core.ExitFatal, code)
+	}
+
+	// Test case 6: Unknown or conflicting output formats
+	if code := execute([]string{"--format", "nope", tempDir}); code != core.ExitFatal {
+		t.Errorf("Expected exit code %d for an unknown format, got %d", core.ExitFatal, code)
+	}
+	if code := execute([]string{"--json", "--format", "text", tempDir}); code != core.ExitFatal {
+		t.Errorf("Expected exit code %d for --json with --format text, got %d", core.ExitFatal, code)
+	}
+	if code := execute([]string{"--color", "sometimes", tempDir}); code != core.ExitFatal {
+		t.Errorf("Expected exit code %d for an invalid color mode, got %d", core.ExitFatal, code)
+	}
+	if code := execute([]string{"--format", "prometheus", "--metrics-repo-label", "name", tempDir}); code != core.ExitFatal {
+		t.Errorf("Expected exit code %d for an invalid metrics label, got %d", core.ExitFatal, code)
+	}
+	if code := execute([]string{"--json", "--format", "json", "--exit-zero", tempDir}); code != core.ExitClean {
+		t.Errorf("Expected exit code %d for --json with --format json, got %d", core.ExitClean, code)
+	}
+
+	// Test case 7: Templates are checked before scanning
+	templateFile := filepath.Join(tempDir, "paths.tmpl")
+	if err := os.WriteFile(templateFile, []byte("{{range .repositories}}{{println .path}}{{end}}"), 0644); err != nil {
+		t.Fatalf("Failed to create template file: %v", err)
+	}
+	for _, args := range [][]string{
+		{"--template", "{{range .repositories}"},
+		{"--template", "{{.path}}", "--format", "json"},
+		{"--template", "{{.path}}", "--template-file", templateFile},
+		{"--template-file", filepath.Join(tempDir, "missing.tmpl")},
+		{"--format", "template"},
+	} {
+		if code := execute(append(args, tempDir)); code != core.ExitFatal {
+			t.Errorf("Expected exit code %d for %v, got %d", core.ExitFatal, args, code)
+		}
+	}
+	if code := execute([]string{"--template-file", templateFile, "--exit-zero", tempDir}); code != core.ExitClean {
+		t.Errorf("Expected exit code %d with a template file, got %d", core.ExitClean, code)
+	}
+}
@@ -1,20 +1,73 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
 
 	"github.com/nguyendangminh/gus/pkg/formatter"
 	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
 	"github.com/nguyendangminh/gus/pkg/scanner"
 )
 
 // Options contains all options for scanning
 type Options struct {
-	Path    string
+	Path string
+	// Format is the name of the output format, see formatter.Names;
+	// empty means text, or json if JSON is set
+	Format  string
 	JSON    bool
 	Verbose bool
+	// Template is the text/template used by the template format
+	Template string
+	// Color tells when output is colored; empty means automatic
+	Color formatter.ColorMode
+	// Group writes one row per repository in the csv and tsv formats
+	Group bool
+	// MetricsRepoLabel tells how the prometheus format labels repositories;
+	// empty means by path
+	MetricsRepoLabel formatter.RepoLabel
+	// MetricsMaxRepos limits the repositories with metrics of their own in
+	// the prometheus format; zero means no limit
+	MetricsMaxRepos int
+	// Jobs is the number of repositories checked in parallel.
+	// Values below 1 default to the number of CPUs.
+	Jobs int
+	// Excludes are gitignore-style patterns of directories to skip
+	Excludes []string
+	// NoDefaultExcludes disables the built-in exclude patterns
+	NoDefaultExcludes bool
+	// IgnoreFile is the global ignore file; empty uses the default location
+	IgnoreFile string
+	// MaxDepth limits how deep the scan descends; zero means no limit
+	MaxDepth int
+	// FollowSymlinks descends into symbolic links to directories
+	FollowSymlinks bool
+	// OneFileSystem keeps the scan on the file system of Path
+	OneFileSystem bool
+	// Unpushed also reports clean repositories with commits not on any remote
+	Unpushed bool
+	// Stashes also reports clean repositories with stashed changes
+	Stashes bool
+	// States restricts the results to repositories in one of these states
+	States []git.State
+	// Timeout limits how long the status of a single repository may take;
+	// zero means no limit
+	Timeout time.Duration
+	// Deadline limits how long the whole scan may take; zero means no limit.
+	// Repositories not checked in time are left out of the report.
+	Deadline time.Duration
+	// OnRepository, if set, is called by Scan with each repository to be
+	// reported as soon as it has been checked. Calls are made one at a time
+	// but from other goroutines, in the order the checks complete.
+	OnRepository func(repo *git.Repository)
 }
 
 // Scanner represents the main scanner
@@ -29,49 +82,306 @@ func New(options Options) *Scanner {
 	}
 }
 
-// Run performs the scanning process
-func (s *Scanner) Run() error {
+// Exit codes returned by Run, from lowest to highest precedence
+const (
+	// ExitClean means no repository was reported
+	ExitClean = 0
+	// ExitDirty means at least one repository was reported
+	ExitDirty = 1
+	// ExitPartial means the status of some repositories could not be checked
+	ExitPartial = 2
+	// ExitFatal means the scan could not be performed at all
+	ExitFatal = 3
+)
+
+// Run scans the path, prints the report to stdout and returns the exit code
+// describing the outcome. An error is only returned together with ExitFatal.
+// When ctx is cancelled the partial report gathered so far is printed.
+func (s *Scanner) Run(ctx context.Context) (int, error) {
+	if sf, ok := formatter.Streaming(s.formatOptions()); ok {
+		return s.runStream(ctx, os.Stdout, sf)
+	}
+
+	result, err := s.Scan(ctx)
+	if err != nil {
+		return ExitFatal, err
+	}
+
+	if err := s.Render(os.Stdout, result); err != nil {
+		return ExitFatal, err
+	}
+
+	return ExitCode(result), nil
+}
+
+// runStream is Run for formats that write repositories as soon as they are
+// checked rather than once the scan is done
+func (s *Scanner) runStream(ctx context.Context, w io.Writer, sf formatter.StreamFormatter) (int, error) {
+	opts := s.formatOptions()
+	partial := &report.Report{Root: s.options.Path, StartTime: time.Now()}
+	if absPath, err := filepath.Abs(s.options.Path); err == nil {
+		partial.Root = absPath
+	}
+
+	var writeErr error
+	streaming := *s
+	streaming.options.OnRepository = func(repo *git.Repository) {
+		if writeErr == nil {
+			writeErr = sf.FormatRepository(w, repo, partial, opts)
+		}
+		if s.options.OnRepository != nil {
+			s.options.OnRepository(repo)
+		}
+	}
+
+	result, err := streaming.Scan(ctx)
+	if err != nil {
+		return ExitFatal, err
+	}
+	if writeErr != nil {
+		return ExitFatal, writeErr
+	}
+
+	if err := sf.FormatSummary(w, result, opts); err != nil {
+		return ExitFatal, err
+	}
+
+	return ExitCode(result), nil
+}
+
+// Scan walks the path, checks every repository found and returns the result
+// without printing anything. If ctx is cancelled or the deadline passes, the
+// repositories checked so far are returned in a report marked Interrupted.
+func (s *Scanner) Scan(ctx context.Context) (*report.Report, error) {
+	start := time.Now()
+
+	if s.options.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.options.Deadline)
+		defer cancel()
+	}
+
 	// Convert to absolute path
 	absPath, err := filepath.Abs(s.options.Path)
 	if err != nil {
-		return fmt.Errorf("invalid path: %w", err)
+		return nil, fmt.Errorf("invalid path: %w", err)
 	}
 
 	// Check if path exists
 	if _, err := os.Stat(absPath); os.IsNotExist(err) {
-		return fmt.Errorf("path does not exist: %s", absPath)
+		return nil, fmt.Errorf("path does not exist: %s", absPath)
 	}
 
-	// Create directory scanner
-	dirScanner := scanner.New(absPath)
-	gitDirs, err := dirScanner.Scan()
-	if err != nil {
-		return fmt.Errorf("scan error: %w", err)
+	// Stream discovered directories straight into the status checkers
+	ignoreFile := s.options.IgnoreFile
+	if ignoreFile == "" {
+		ignoreFile = scanner.DefaultIgnoreFile()
+	}
+	dirScanner := scanner.New(absPath,
+		scanner.WithExcludes(s.options.Excludes...),
+		scanner.WithIgnoreFile(ignoreFile),
+		scanner.WithDefaultExcludes(!s.options.NoDefaultExcludes),
+		scanner.WithMaxDepth(s.options.MaxDepth),
+		scanner.WithFollowSymlinks(s.options.FollowSymlinks),
+		scanner.WithOneFileSystem(s.options.OneFileSystem),
+	)
+	found, scanErr := dirScanner.Stream(ctx)
+
+	paths := make(chan string)
+	go func() {
+		defer close(paths)
+		for repo := range found {
+			paths <- repo.Path
+		}
+	}()
+
+	var onResult func(repo *git.Repository)
+	if s.options.OnRepository != nil {
+		onResult = func(repo *git.Repository) {
+			if s.report(repo) {
+				s.options.OnRepository(repo)
+			}
+		}
+	}
+
+	repos, errs := checkRepositories(ctx, paths, s.jobs(), s.options.Timeout, onResult)
+	if err := <-scanErr; err != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("scan error: %w", err)
+	}
+
+	result := &report.Report{
+		Root:        absPath,
+		Warnings:    dirScanner.Warnings(),
+		Errors:      errs,
+		StartTime:   start,
+		Interrupted: ctx.Err() != nil,
+	}
+	for _, repo := range repos {
+		if s.report(repo) {
+			result.Repositories = append(result.Repositories, repo)
+		} else {
+			result.Clean = append(result.Clean, repo)
+		}
+	}
+	result.Duration = time.Since(start)
+
+	return result, nil
+}
+
+// Render writes a report to w in the output format of the options
+func (s *Scanner) Render(w io.Writer, result *report.Report) error {
+	return formatter.Format(w, result, s.formatOptions())
+}
+
+// formatOptions returns the formatter options of the scanner options
+func (s *Scanner) formatOptions() formatter.FormatOptions {
+	return formatter.FormatOptions{
+		Format:   s.options.Format,
+		JSON:     s.options.JSON,
+		Verbose:  s.options.Verbose,
+		Template: s.options.Template,
+		Color:    s.options.Color,
+		Group:    s.options.Group,
+
+		MetricsRepoLabel: s.options.MetricsRepoLabel,
+		MetricsMaxRepos:  s.options.MetricsMaxRepos,
 	}
+}
 
-	if s.options.Verbose {
-		fmt.Printf("Found %d Git repositories\n", len(gitDirs))
+// ExitCode returns the exit code describing a report: ExitPartial if the scan
+// was interrupted or some repositories could not be checked, ExitDirty if any
+// were reported and ExitClean otherwise
+func ExitCode(result *report.Report) int {
+	switch {
+	case result.Interrupted, len(result.Errors) > 0:
+		return ExitPartial
+	case len(result.Repositories) > 0:
+		return ExitDirty
 	}
+	return ExitClean
+}
 
-	// Check status of each repository
-	var reposWithChanges []*git.Repository
-	for _, dir := range gitDirs {
-		repo, err := git.CheckStatus(dir)
-		if err != nil {
-			if s.options.Verbose {
-				fmt.Fprintf(os.Stderr, "Warning: failed to check status of %s: %v\n", dir, err)
+// report reports whether a repository should be listed in the results
+func (s *Scanner) report(repo *git.Repository) bool {
+	if len(s.options.States) > 0 {
+		for _, state := range s.options.States {
+			if repo.State == state {
+				return true
 			}
-			continue
 		}
+		return false
+	}
+
+	switch {
+	case repo.IsDirty():
+		return true
+	case s.options.Unpushed && repo.HasUnpushed():
+		return true
+	case s.options.Stashes && repo.HasStashes():
+		return true
+	}
+	return false
+}
+
+// jobs returns the effective size of the worker pool
+func (s *Scanner) jobs() int {
+	if s.options.Jobs < 1 {
+		return runtime.NumCPU()
+	}
+	return s.options.Jobs
+}
+
+// checkRepositories checks the status of every directory received on paths
+// using a pool of workers. Repositories and errors are returned in the order
+// the directories were received. Each check is limited by timeout unless it
+// is zero; once ctx is done the remaining directories are left out. If
+// onResult is not nil it is called with each repository as soon as it has
+// been checked, one call at a time.
+func checkRepositories(ctx context.Context, paths <-chan string, jobs int, timeout time.Duration, onResult func(*git.Repository)) ([]*git.Repository, []*report.RepoError) {
+	type job struct {
+		index int
+		path  string
+	}
+	type result struct {
+		repo *git.Repository
+		err  *report.RepoError
+	}
+
+	var (
+		mu      sync.Mutex
+		results = make(map[int]result)
+		wg      sync.WaitGroup
+	)
+
+	queue := make(chan job)
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				if ctx.Err() != nil {
+					continue
+				}
+				var r result
+				repo, err := checkRepository(ctx, j.path, timeout)
+				switch {
+				case err == nil:
+					r.repo = repo
+				case ctx.Err() != nil:
+					// Interrupted, the repository was not checked
+					continue
+				case errors.Is(err, context.DeadlineExceeded):
+					r.err = &report.RepoError{
+						Path:     j.path,
+						Err:      fmt.Errorf("timed out after %s", timeout),
+						TimedOut: true,
+					}
+				default:
+					r.err = &report.RepoError{Path: j.path, Err: err}
+				}
+				mu.Lock()
+				results[j.index] = r
+				if r.repo != nil && onResult != nil {
+					onResult(r.repo)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
 
-		if len(repo.Changes) > 0 {
-			reposWithChanges = append(reposWithChanges, repo)
+	count := 0
+	for path := range paths {
+		queue <- job{index: count, path: path}
+		count++
+	}
+	close(queue)
+	wg.Wait()
+
+	var (
+		checked []*git.Repository
+		failed  []*report.RepoError
+	)
+	for i := 0; i < count; i++ {
+		r, ok := results[i]
+		if !ok {
+			continue
 		}
+		if r.err != nil {
+			failed = append(failed, r.err)
+			continue
+		}
+		checked = append(checked, r.repo)
 	}
 
-	// Format and print results
-	opts := formatter.FormatOptions{
-		JSON: s.options.JSON,
+	return checked, failed
+}
+
+// checkRepository checks the status of a repository within timeout
+func checkRepository(ctx context.Context, path string, timeout time.Duration) (*git.Repository, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
-	return formatter.FormatRepositories(reposWithChanges, opts)
+	return git.CheckStatusContext(ctx, path)
 }
@@ -1,10 +1,18 @@
 package core
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/nguyendangminh/gus/pkg/formatter"
+	"github.com/nguyendangminh/gus/pkg/git"
 )
 
 func TestScanner_Run(t *testing.T) {
@@ -54,10 +62,13 @@ func TestScanner_Run(t *testing.T) {
 		Path: tempDir,
 	}
 	scanner := New(options)
-	err = scanner.Run()
+	code, err := scanner.Run(context.Background())
 	if err != nil {
 		t.Errorf("Run failed: %v", err)
 	}
+	if code != ExitDirty {
+		t.Errorf("Expected exit code %d, got %d", ExitDirty, code)
+	}
 
 	// Test case 2: JSON output
 	options = Options{
@@ -65,7 +76,7 @@ func TestScanner_Run(t *testing.T) {
 		JSON: true,
 	}
 	scanner = New(options)
-	err = scanner.Run()
+	_, err = scanner.Run(context.Background())
 	if err != nil {
 		t.Errorf("Run failed with JSON output: %v", err)
 	}
@@ -76,7 +87,7 @@ func TestScanner_Run(t *testing.T) {
 		Verbose: true,
 	}
 	scanner = New(options)
-	err = scanner.Run()
+	_, err = scanner.Run(context.Background())
 	if err != nil {
 		t.Errorf("Run failed with verbose output: %v", err)
 	}
@@ -86,8 +97,314 @@ func TestScanner_Run(t *testing.T) {
 		Path: "/invalid/path",
 	}
 	scanner = New(options)
-	err = scanner.Run()
+	code, err = scanner.Run(context.Background())
 	if err == nil {
 		t.Error("Expected error for invalid path")
 	}
+	if code != ExitFatal {
+		t.Errorf("Expected exit code %d, got %d", ExitFatal, code)
+	}
+
+	// Test case 5: Repositories that cannot be checked
+	if err := os.MkdirAll(filepath.Join(tempDir, "broken", ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	scanner = New(Options{Path: tempDir})
+	code, err = scanner.Run(context.Background())
+	if err != nil {
+		t.Errorf("Run failed: %v", err)
+	}
+	if code != ExitPartial {
+		t.Errorf("Expected exit code %d, got %d", ExitPartial, code)
+	}
+}
+
+func TestCheckRepositories(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "core-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize several Git repositories, one of them dirty
+	var dirs []string
+	for _, name := range []string{"repo1", "repo2", "repo3", "repo4"} {
+		dir := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory %s: %v", name, err)
+		}
+		cmd := exec.Command("git", "init")
+		cmd.Dir = dir
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Failed to initialize Git repository in %s: %v", name, err)
+		}
+		dirs = append(dirs, dir)
+	}
+	if err := os.WriteFile(filepath.Join(dirs[2], "test.txt"), []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Add a directory that cannot be checked
+	missing := filepath.Join(tempDir, "missing")
+	dirs = append(dirs[:1], append([]string{missing}, dirs[1:]...)...)
+
+	for _, jobs := range []int{1, 2, 8} {
+		repos, errs := checkRepositories(context.Background(), feed(dirs), jobs, 0, nil)
+
+		// Test case 1: Results keep the order of the input
+		if len(repos) != 4 {
+			t.Fatalf("jobs=%d: expected 4 repositories, got %d", jobs, len(repos))
+		}
+		expected := []string{dirs[0], dirs[2], dirs[3], dirs[4]}
+		for i, repo := range repos {
+			if repo.Path != expected[i] {
+				t.Errorf("jobs=%d: expected repository %d to be %s, got %s", jobs, i, expected[i], repo.Path)
+			}
+		}
+		if len(repos[2].Changes) == 0 {
+			t.Errorf("jobs=%d: expected changes in %s", jobs, repos[2].Path)
+		}
+
+		// Test case 2: Errors are collected instead of dropped
+		if len(errs) != 1 {
+			t.Fatalf("jobs=%d: expected 1 error, got %d", jobs, len(errs))
+		}
+		if errs[0].Path != missing {
+			t.Errorf("jobs=%d: expected error for %s, got %s", jobs, missing, errs[0].Path)
+		}
+	}
+
+	// Test case 3: No directories
+	repos, errs := checkRepositories(context.Background(), feed(nil), 4, 0, nil)
+	if len(repos) != 0 || len(errs) != 0 {
+		t.Errorf("Expected no results for no directories, got %d repos and %d errors", len(repos), len(errs))
+	}
+
+	// Test case 4: Repositories that take too long are marked as timed out
+	repos, errs = checkRepositories(context.Background(), feed(dirs[:1]), 1, time.Nanosecond, nil)
+	if len(repos) != 0 || len(errs) != 1 {
+		t.Fatalf("Expected 1 error with a timeout, got %d repos and %d errors", len(repos), len(errs))
+	}
+	if !errs[0].TimedOut {
+		t.Errorf("Expected %s to be marked as timed out: %v", errs[0].Path, errs[0].Err)
+	}
+
+	// Test case 5: Nothing is checked once the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	repos, errs = checkRepositories(ctx, feed(dirs), 2, 0, nil)
+	if len(repos) != 0 || len(errs) != 0 {
+		t.Errorf("Expected no results after cancellation, got %d repos and %d errors", len(repos), len(errs))
+	}
+}
+
+// feed returns a closed channel that yields the given directories
+func feed(dirs []string) <-chan string {
+	paths := make(chan string, len(dirs))
+	for _, dir := range dirs {
+		paths <- dir
+	}
+	close(paths)
+	return paths
+}
+
+func TestScanner_Report(t *testing.T) {
+	dirty := &git.Repository{Path: "/dirty", Changes: []git.Change{
+		git.NewChange(git.StatusUntracked, git.StatusUntracked, "new.txt", ""),
+	}}
+	ahead := &git.Repository{Path: "/ahead", Branch: "main", Upstream: "origin/main", Ahead: 1}
+	unpushed := &git.Repository{Path: "/unpushed", UnpushedBranches: []git.UnpushedBranch{{Name: "feature", Commits: 2}}}
+	stashed := &git.Repository{Path: "/stashed", Stashes: []git.Stash{{Index: 0, Message: "WIP on main"}}}
+	clean := &git.Repository{Path: "/clean"}
+
+	// Test case 1: Only dirty repositories by default
+	s := New(Options{})
+	for repo, want := range map[*git.Repository]bool{dirty: true, ahead: false, unpushed: false, stashed: false, clean: false} {
+		if got := s.report(repo); got != want {
+			t.Errorf("%s: expected report=%v, got %v", repo.Path, want, got)
+		}
+	}
+
+	// Test case 2: Clean repositories with unpushed work
+	s = New(Options{Unpushed: true})
+	for repo, want := range map[*git.Repository]bool{dirty: true, ahead: true, unpushed: true, stashed: false, clean: false} {
+		if got := s.report(repo); got != want {
+			t.Errorf("%s: expected report=%v with Unpushed, got %v", repo.Path, want, got)
+		}
+	}
+
+	// Test case 3: Filter by state
+	rebasing := &git.Repository{Path: "/rebasing", State: git.StateRebasing}
+	detached := &git.Repository{Path: "/detached", State: git.StateDetached, Changes: dirty.Changes}
+	s = New(Options{States: []git.State{git.StateRebasing, git.StateMerging}})
+	for repo, want := range map[*git.Repository]bool{dirty: false, rebasing: true, detached: false, clean: false} {
+		if got := s.report(repo); got != want {
+			t.Errorf("%s: expected report=%v with States, got %v", repo.Path, want, got)
+		}
+	}
+
+	// Test case 4: Repositories with stashes treated as dirty
+	s = New(Options{Stashes: true})
+	for repo, want := range map[*git.Repository]bool{dirty: true, ahead: false, unpushed: false, stashed: true, clean: false} {
+		if got := s.report(repo); got != want {
+			t.Errorf("%s: expected report=%v with Stashes, got %v", repo.Path, want, got)
+		}
+	}
+}
+
+func TestScanner_Scan(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "core-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	tempDir, _ = filepath.EvalSymlinks(tempDir)
+
+	// Initialize a dirty and a clean repository
+	for _, dir := range []string{"dirty", "clean"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory %s: %v", dir, err)
+		}
+		cmd := exec.Command("git", "init")
+		cmd.Dir = filepath.Join(tempDir, dir)
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Failed to initialize Git repository in %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "dirty", "test.txt"), []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Test case 1: Scan returns the result without printing it
+	s := New(Options{Path: tempDir, JSON: true})
+	result, err := s.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.Root != tempDir {
+		t.Errorf("Expected root %s, got %s", tempDir, result.Root)
+	}
+	if len(result.Repositories) != 1 || result.Repositories[0].Path != filepath.Join(tempDir, "dirty") {
+		t.Errorf("Expected the dirty repository to be reported, got %v", result.Repositories)
+	}
+	if len(result.Clean) != 1 || result.Clean[0].Path != filepath.Join(tempDir, "clean") {
+		t.Errorf("Expected the clean repository to be listed as clean, got %v", result.Clean)
+	}
+	if result.Total() != 2 {
+		t.Errorf("Expected 2 repositories in total, got %d", result.Total())
+	}
+	if result.StartTime.IsZero() || result.Duration <= 0 {
+		t.Errorf("Expected scan timings, got %v and %v", result.StartTime, result.Duration)
+	}
+	if code := ExitCode(result); code != ExitDirty {
+		t.Errorf("Expected exit code %d, got %d", ExitDirty, code)
+	}
+
+	// Test case 2: Render writes to any writer
+	var buf bytes.Buffer
+	if err := s.Render(&buf, result); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	var output map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Failed to parse rendered JSON: %v\n%s", err, buf.String())
+	}
+	if repos, _ := output["repositories"].([]interface{}); len(repos) != 1 {
+		t.Errorf("Expected 1 rendered repository, got %v", output["repositories"])
+	}
+
+	// Test case 3: Invalid path
+	if _, err := New(Options{Path: "/invalid/path"}).Scan(context.Background()); err == nil {
+		t.Error("Expected error for invalid path")
+	}
+
+	// Test case 4: A cancelled scan returns a partial report
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = s.Scan(ctx)
+	if err != nil {
+		t.Fatalf("Expected a partial report after cancellation, got %v", err)
+	}
+	if !result.Interrupted {
+		t.Error("Expected the report to be marked as interrupted")
+	}
+	if code := ExitCode(result); code != ExitPartial {
+		t.Errorf("Expected exit code %d, got %d", ExitPartial, code)
+	}
+}
+
+func TestScanner_Stream(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "core-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	tempDir, _ = filepath.EvalSymlinks(tempDir)
+
+	// Initialize two dirty repositories and a clean one
+	for _, dir := range []string{"dirty1", "dirty2", "clean"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory %s: %v", dir, err)
+		}
+		cmd := exec.Command("git", "init")
+		cmd.Dir = filepath.Join(tempDir, dir)
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Failed to initialize Git repository in %s: %v", dir, err)
+		}
+		if dir != "clean" {
+			if err := os.WriteFile(filepath.Join(tempDir, dir, "test.txt"), []byte("test"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+		}
+	}
+
+	// Test case 1: OnRepository sees the reported repositories only
+	var seen []string
+	s := New(Options{Path: tempDir, OnRepository: func(repo *git.Repository) {
+		seen = append(seen, repo.Path)
+	}})
+	result, err := s.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(seen) != 2 || len(result.Repositories) != 2 {
+		t.Errorf("Expected 2 streamed and reported repositories, got %v and %d", seen, len(result.Repositories))
+	}
+
+	// Test case 2: Stream formats write each repository, then a summary
+	s = New(Options{Path: tempDir, Format: "ndjson"})
+	sf, ok := formatter.Streaming(s.formatOptions())
+	if !ok {
+		t.Fatal("Expected ndjson to be a stream format")
+	}
+	var buf bytes.Buffer
+	code, err := s.runStream(context.Background(), &buf, sf)
+	if err != nil {
+		t.Fatalf("runStream failed: %v", err)
+	}
+	if code != ExitDirty {
+		t.Errorf("Expected exit code %d, got %d", ExitDirty, code)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 records, got %d:\n%s", len(lines), buf.String())
+	}
+	for i, line := range lines {
+		var record struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to parse record %q: %v", line, err)
+		}
+		expected := "repository"
+		if i == len(lines)-1 {
+			expected = "summary"
+		}
+		if record.Type != expected {
+			t.Errorf("Expected record %d to be a %s, got %q", i, expected, record.Type)
+		}
+	}
 }
@@ -0,0 +1,98 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+)
+
+// ColorMode tells when output is colored
+type ColorMode string
+
+const (
+	// ColorAuto colors output written to a terminal unless NO_COLOR is set
+	ColorAuto ColorMode = "auto"
+	// ColorAlways colors output even when it is redirected
+	ColorAlways ColorMode = "always"
+	// ColorNever never colors output
+	ColorNever ColorMode = "never"
+)
+
+// ParseColorMode converts the value of the --color flag to a ColorMode
+func ParseColorMode(s string) (ColorMode, error) {
+	switch mode := ColorMode(s); mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid color mode %q (expected auto, always or never)", s)
+}
+
+// useColor reports whether output written to w should be colored. An empty
+// mode behaves like ColorAuto. See https://no-color.org for NO_COLOR.
+func (o FormatOptions) useColor(w io.Writer) bool {
+	switch o.Color {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// colors are the ANSI escape sequences of the named colors
+var colors = map[string]string{
+	"bold":    "\x1b[1m",
+	"red":     "\x1b[31m",
+	"green":   "\x1b[32m",
+	"yellow":  "\x1b[33m",
+	"blue":    "\x1b[34m",
+	"magenta": "\x1b[35m",
+	"cyan":    "\x1b[36m",
+	"grey":    "\x1b[90m",
+	"gray":    "\x1b[90m",
+}
+
+// Styles of the kinds of changes
+const (
+	styleStaged    = "\x1b[32m"
+	styleUnstaged  = "\x1b[31m"
+	styleUntracked = "\x1b[90m"
+	styleConflict  = "\x1b[1;31m"
+)
+
+// colorReset ends a colored span
+const colorReset = "\x1b[0m"
+
+// colorizer wraps text in ANSI escape sequences if enabled
+type colorizer bool
+
+// wrap wraps s in the escape sequence style
+func (c colorizer) wrap(style, s string) string {
+	if !c || style == "" {
+		return s
+	}
+	return style + s + colorReset
+}
+
+// changeStyle returns the style of a change: conflicts are bold red,
+// untracked files grey, changes with unstaged parts red and fully staged
+// changes green
+func changeStyle(change git.Change) string {
+	switch {
+	case change.Kind == git.ChangeUnmerged:
+		return styleConflict
+	case change.Kind == git.ChangeUntracked:
+		return styleUntracked
+	case change.Unstaged():
+		return styleUnstaged
+	case change.Staged():
+		return styleStaged
+	}
+	return ""
+}
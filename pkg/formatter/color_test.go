@@ -0,0 +1,119 @@
+package formatter
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+// ansi matches ANSI escape sequences
+var ansi = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestParseColorMode(t *testing.T) {
+	for _, s := range []string{"auto", "always", "never"} {
+		if mode, err := ParseColorMode(s); err != nil || string(mode) != s {
+			t.Errorf("ParseColorMode(%q): got %q, %v", s, mode, err)
+		}
+	}
+	if _, err := ParseColorMode("sometimes"); err == nil {
+		t.Error("Expected an error for an invalid color mode")
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	var buf bytes.Buffer
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+
+	if (FormatOptions{}).useColor(&buf) {
+		t.Error("Expected no color for a buffer in auto mode")
+	}
+	if !(FormatOptions{Color: ColorAlways}).useColor(&buf) {
+		t.Error("Expected color with ColorAlways")
+	}
+
+	// Regular files are not terminals
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer f.Close()
+	if (FormatOptions{Color: ColorAuto}).useColor(f) {
+		t.Error("Expected no color for a regular file in auto mode")
+	}
+
+	// NO_COLOR disables auto but not always
+	t.Setenv("NO_COLOR", "1")
+	if !(FormatOptions{Color: ColorAlways}).useColor(&buf) {
+		t.Error("Expected --color=always to override NO_COLOR")
+	}
+	if (FormatOptions{Color: ColorNever}).useColor(&buf) {
+		t.Error("Expected no color with ColorNever")
+	}
+}
+
+func TestFormatColor(t *testing.T) {
+	r := &report.Report{
+		Root: "/src",
+		Repositories: []*git.Repository{{
+			Path:   "/src/app",
+			Branch: "main",
+			Changes: []git.Change{
+				git.NewChange(git.StatusAdded, git.StatusUnmodified, "staged.go", ""),
+				git.NewChange(git.StatusUnmodified, git.StatusModified, "unstaged.go", ""),
+				git.NewChange(git.StatusUntracked, git.StatusUntracked, "untracked.go", ""),
+				git.NewChange(git.StatusUnmerged, git.StatusUnmerged, "conflict.go", ""),
+			},
+		}},
+		StartTime: scanTime,
+	}
+
+	// Test case 1: Change kinds are colored in text output
+	var buf bytes.Buffer
+	if err := Format(&buf, r, FormatOptions{Color: ColorAlways}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	for _, s := range []string{
+		styleStaged + "added: staged.go" + colorReset,
+		styleUnstaged + "modified: unstaged.go" + colorReset,
+		styleUntracked + "untracked: untracked.go" + colorReset,
+		styleConflict + "unmerged: conflict.go" + colorReset,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Expected output to contain %q, got %q", s, buf.String())
+		}
+	}
+
+	// Test case 2: Colors do not change the output otherwise
+	var plain bytes.Buffer
+	if err := Format(&plain, r, FormatOptions{Color: ColorNever}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if ansi.MatchString(plain.String()) {
+		t.Errorf("Expected no escape sequences with ColorNever, got %q", plain.String())
+	}
+	if got := ansi.ReplaceAllString(buf.String(), ""); got != plain.String() {
+		t.Errorf("Expected colored output to match plain output:\n%s\n%s", got, plain.String())
+	}
+
+	// Test case 3: Colored tables stay aligned
+	buf.Reset()
+	plain.Reset()
+	if err := Format(&buf, r, FormatOptions{Format: "table", Color: ColorAlways, Width: -1}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if err := Format(&plain, r, FormatOptions{Format: "table", Color: ColorNever, Width: -1}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), styleStaged) {
+		t.Errorf("Expected colored counts, got %q", buf.String())
+	}
+	if got := ansi.ReplaceAllString(buf.String(), ""); got != plain.String() {
+		t.Errorf("Expected colored table to match plain table:\n%s\n%s", got, plain.String())
+	}
+}
@@ -0,0 +1,74 @@
+package formatter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("csv", delimitedFormatter{comma: ','})
+	Register("tsv", delimitedFormatter{comma: '\t'})
+}
+
+// Column titles of the csv and tsv formats
+var (
+	changeColumns = []string{"repository", "branch", "path", "index", "worktree"}
+	groupColumns  = []string{"repository", "branch", "upstream", "state", "staged", "unstaged", "untracked", "ahead", "behind", "stashes"}
+)
+
+// delimitedFormatter writes delimiter-separated values with a header row:
+// one row per changed file, or one row per repository when grouped. Fields
+// are quoted as described in RFC 4180 when needed.
+type delimitedFormatter struct {
+	comma rune
+}
+
+// Format writes the report as delimiter-separated values
+func (f delimitedFormatter) Format(w io.Writer, r *report.Report, opts FormatOptions) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = f.comma
+
+	if opts.Group {
+		cw.Write(groupColumns)
+		for _, repo := range r.Repositories {
+			c := countChanges(repo.Changes)
+			cw.Write([]string{
+				repo.Path,
+				repo.Branch,
+				repo.Upstream,
+				string(repo.State),
+				strconv.Itoa(c.staged),
+				strconv.Itoa(c.unstaged),
+				strconv.Itoa(c.untracked),
+				strconv.Itoa(repo.Ahead),
+				strconv.Itoa(repo.Behind),
+				strconv.Itoa(len(repo.Stashes)),
+			})
+		}
+	} else {
+		cw.Write(changeColumns)
+		for _, repo := range r.Repositories {
+			for _, change := range repo.Changes {
+				cw.Write(changeRow(repo, change))
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// changeRow returns the row of a changed file
+func changeRow(repo *git.Repository, change git.Change) []string {
+	return []string{
+		repo.Path,
+		repo.Branch,
+		change.Path,
+		change.Index.String(),
+		change.Worktree.String(),
+	}
+}
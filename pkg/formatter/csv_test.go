@@ -0,0 +1,85 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func TestFormatDelimited(t *testing.T) {
+	r := &report.Report{
+		Repositories: []*git.Repository{
+			{
+				Path:   "/src/app",
+				Branch: "main",
+				Changes: []git.Change{
+					git.NewChange(git.StatusModified, git.StatusModified, "main.go", ""),
+					git.NewChange(git.StatusUntracked, git.StatusUntracked, "odd, \"quoted\"\nname.txt", ""),
+				},
+			},
+			{
+				Path:    "/src/tab\tbed",
+				Branch:  "dev",
+				Ahead:   3,
+				Stashes: []git.Stash{{Index: 0, Message: "WIP"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		format   string
+		group    bool
+		expected [][]string
+	}{
+		{
+			format: "csv",
+			expected: [][]string{
+				{"repository", "branch", "path", "index", "worktree"},
+				{"/src/app", "main", "main.go", "M", "M"},
+				{"/src/app", "main", "odd, \"quoted\"\nname.txt", "?", "?"},
+			},
+		},
+		{
+			format: "tsv",
+			group:  true,
+			expected: [][]string{
+				{"repository", "branch", "upstream", "state", "staged", "unstaged", "untracked", "ahead", "behind", "stashes"},
+				{"/src/app", "main", "", "", "1", "1", "1", "0", "0", "0"},
+				{"/src/tab\tbed", "dev", "", "", "0", "0", "0", "3", "0", "1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Format(&buf, r, FormatOptions{Format: tt.format, Group: tt.group}); err != nil {
+			t.Fatalf("%s: Format failed: %v", tt.format, err)
+		}
+
+		// Reading the output back must give the original fields
+		reader := csv.NewReader(bytes.NewReader(buf.Bytes()))
+		if tt.format == "tsv" {
+			reader.Comma = '\t'
+		}
+		records, err := reader.ReadAll()
+		if err != nil {
+			t.Fatalf("%s: failed to parse output: %v\n%s", tt.format, err, buf.String())
+		}
+		if !reflect.DeepEqual(records, tt.expected) {
+			t.Errorf("%s: expected %q, got %q", tt.format, tt.expected, records)
+		}
+	}
+
+	// Fields with delimiters, quotes or newlines are quoted, quotes doubled
+	var buf bytes.Buffer
+	if err := Format(&buf, r, FormatOptions{Format: "csv"}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("\"odd, \"\"quoted\"\"\nname.txt\"")) {
+		t.Errorf("Expected RFC 4180 quoting, got %q", buf.String())
+	}
+}
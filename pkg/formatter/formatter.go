@@ -1,100 +1,169 @@
+// Package formatter renders scan reports. Formats are looked up by name in a
+// registry that the built-in formats and third-party code add to with Register.
 package formatter
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
 )
 
+// Version is the version reported in the output metadata
+const Version = "1.0.0"
+
+// DefaultFormat is the format used when none is given
+const DefaultFormat = "text"
+
 // FormatOptions contains options for formatting output
 type FormatOptions struct {
-	JSON bool
+	// Format is the name of a registered format; empty means DefaultFormat
+	Format string
+	// JSON is a shorthand for Format "json", kept for compatibility
+	JSON    bool
+	Verbose bool
+	// Template is the text/template used by the template format
+	Template string
+	// Width is the number of columns tables are fitted to: zero uses the
+	// width of the terminal, a negative width means no limit
+	Width int
+	// Color tells when output is colored; empty means ColorAuto
+	Color ColorMode
+	// Group writes one row per repository instead of one per changed file
+	// in the csv and tsv formats
+	Group bool
+	// MetricsRepoLabel tells how the prometheus format labels repositories;
+	// empty means RepoLabelPath
+	MetricsRepoLabel RepoLabel
+	// MetricsMaxRepos limits the prometheus format to the series of the
+	// repositories with the most changes; zero means no limit
+	MetricsMaxRepos int
 }
 
-// FormatRepositories formats the list of repositories according to the options
-func FormatRepositories(repos []*git.Repository, opts FormatOptions) error {
-	if len(repos) == 0 {
-		fmt.Println("No Git repositories with uncommitted changes found.")
-		return nil
+// name returns the name of the format selected by the options
+func (o FormatOptions) name() string {
+	switch {
+	case o.Format != "":
+		return o.Format
+	case o.JSON:
+		return "json"
+	case o.Template != "":
+		return "template"
 	}
+	return DefaultFormat
+}
 
-	if opts.JSON {
-		return formatJSON(repos)
-	}
+// Formatter renders a report in one output format
+type Formatter interface {
+	Format(w io.Writer, r *report.Report, opts FormatOptions) error
+}
 
-	return formatText(repos)
+// FormatterFunc adapts an ordinary function to the Formatter interface
+type FormatterFunc func(w io.Writer, r *report.Report, opts FormatOptions) error
+
+// Format calls f(w, r, opts)
+func (f FormatterFunc) Format(w io.Writer, r *report.Report, opts FormatOptions) error {
+	return f(w, r, opts)
 }
 
-// formatJSON formats the repositories as JSON
-func formatJSON(repos []*git.Repository) error {
-	type repoJSON struct {
-		Path       string    `json:"path"`
-		Changes    []string  `json:"changes"`
-		ScanTime   time.Time `json:"scan_time"`
-		TotalRepos int       `json:"total_repositories"`
-	}
+// StreamFormatter is a Formatter that can also write each repository as
+// soon as it has been checked, followed by a summary once the scan is done.
+// The report passed to FormatRepository is incomplete: only Root and
+// StartTime are set.
+type StreamFormatter interface {
+	Formatter
+	FormatRepository(w io.Writer, repo *git.Repository, r *report.Report, opts FormatOptions) error
+	FormatSummary(w io.Writer, r *report.Report, opts FormatOptions) error
+}
 
-	jsonRepos := make([]repoJSON, len(repos))
-	for i, repo := range repos {
-		jsonRepos[i] = repoJSON{
-			Path:       repo.Path,
-			Changes:    repo.Changes,
-			ScanTime:   time.Now(),
-			TotalRepos: len(repos),
-		}
+// Streaming returns the formatter selected by the options if it is a
+// StreamFormatter
+func Streaming(opts FormatOptions) (StreamFormatter, bool) {
+	f, err := Lookup(opts.name())
+	if err != nil {
+		return nil, false
 	}
+	sf, ok := f.(StreamFormatter)
+	return sf, ok
+}
 
-	// Create a wrapper structure for the entire output
-	type outputJSON struct {
-		Repositories []repoJSON `json:"repositories"`
-		Metadata     struct {
-			ScanTime   time.Time `json:"scan_time"`
-			TotalRepos int       `json:"total_repositories"`
-			Version    string    `json:"version"`
-		} `json:"metadata"`
+// Format writes the report to w using the format selected by the options
+func Format(w io.Writer, r *report.Report, opts FormatOptions) error {
+	f, err := Lookup(opts.name())
+	if err != nil {
+		return err
 	}
+	return f.Format(w, r, opts)
+}
 
-	output := outputJSON{
-		Repositories: jsonRepos,
-		Metadata: struct {
-			ScanTime   time.Time `json:"scan_time"`
-			TotalRepos int       `json:"total_repositories"`
-			Version    string    `json:"version"`
-		}{
-			ScanTime:   time.Now(),
-			TotalRepos: len(repos),
-			Version:    "1.0.0",
-		},
+// reportTime returns the time the report was made, which ages are relative to
+func reportTime(r *report.Report) time.Time {
+	if r.StartTime.IsZero() {
+		return time.Now()
 	}
-
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(output)
+	return r.StartTime
 }
 
-// formatText formats the repositories as text
-func formatText(repos []*git.Repository) error {
-	fmt.Printf("Found %d Git repositories with uncommitted changes:\n\n", len(repos))
-
-	for i, repo := range repos {
-		// Format repository path
-		path := repo.Path
-		if strings.HasPrefix(path, os.Getenv("HOME")) {
-			path = "~" + path[len(os.Getenv("HOME")):]
-		}
+// plural formats a count followed by a noun in singular or plural form,
+// following the regular rules of English: stash, stashes; repository,
+// repositories
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	switch {
+	case strings.HasSuffix(noun, "s"), strings.HasSuffix(noun, "x"),
+		strings.HasSuffix(noun, "sh"), strings.HasSuffix(noun, "ch"):
+		noun += "es"
+	case strings.HasSuffix(noun, "y") && len(noun) > 1 && !strings.ContainsRune("aeiou", rune(noun[len(noun)-2])):
+		noun = noun[:len(noun)-1] + "ies"
+	default:
+		noun += "s"
+	}
+	return fmt.Sprintf("%d %s", n, noun)
+}
 
-		fmt.Printf("%d. %s\n", i+1, path)
+// formatAge formats a duration as a rough relative time such as "3 days ago"
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute") + " ago"
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour") + " ago"
+	case d < 30*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day") + " ago"
+	case d < 365*24*time.Hour:
+		return plural(int(d/(30*24*time.Hour)), "month") + " ago"
+	default:
+		return plural(int(d/(365*24*time.Hour)), "year") + " ago"
+	}
+}
 
-		// Format changes
-		for _, change := range repo.Changes {
-			fmt.Printf("   - %s\n", change)
-		}
-		fmt.Println()
+// relPath returns path relative to root, or path itself if it is not
+// below root
+func relPath(root, path string) string {
+	if root == "" {
+		return path
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
 	}
+	return rel
+}
 
-	return nil
+// shortenPath replaces the home directory prefix of a path with ~
+func shortenPath(path string) string {
+	home := os.Getenv("HOME")
+	if home != "" && strings.HasPrefix(path, home) {
+		return "~" + path[len(home):]
+	}
+	return path
 }
@@ -3,67 +3,74 @@ package formatter
 import (
 	"bytes"
 	"encoding/json"
-	"os"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+	"github.com/nguyendangminh/gus/pkg/scanner"
 )
 
-func TestFormatRepositories(t *testing.T) {
+// scanTime is the time test reports were made
+var scanTime = time.Date(2024, 3, 20, 10, 30, 0, 0, time.UTC)
+
+func TestFormat(t *testing.T) {
 	// Create test repositories
 	repos := []*git.Repository{
 		{
 			Path: "/path/to/repo1",
-			Changes: []string{
-				"modified: file1.txt",
-				"added: file2.txt",
+			Changes: []git.Change{
+				git.NewChange(git.StatusModified, git.StatusUnmodified, "file1.txt", ""),
+				git.NewChange(git.StatusAdded, git.StatusUnmodified, "file2.txt", ""),
 			},
 		},
 		{
 			Path: "/path/to/repo2",
-			Changes: []string{
-				"deleted: old_file.txt",
+			Changes: []git.Change{
+				git.NewChange(git.StatusUnmodified, git.StatusDeleted, "old_file.txt", ""),
+			},
+			Stashes: []git.Stash{
+				{Index: 0, Message: "WIP on main: 1234abc subject", Time: scanTime.Add(-2 * time.Hour)},
 			},
 		},
 	}
+	r := &report.Report{
+		Root:         "/path/to",
+		Repositories: repos,
+		Clean:        []*git.Repository{{Path: "/path/to/clean"}},
+		StartTime:    scanTime,
+		Duration:     1500 * time.Millisecond,
+	}
 
 	// Test case 1: Empty repositories
-	err := FormatRepositories([]*git.Repository{}, FormatOptions{})
+	var buf bytes.Buffer
+	err := Format(&buf, &report.Report{}, FormatOptions{})
 	if err != nil {
-		t.Errorf("FormatRepositories failed with empty repos: %v", err)
+		t.Errorf("Format failed with empty repos: %v", err)
 	}
-
-	// Test case 2: Text format
-	// Redirect stdout to capture output
-	oldStdout := os.Stdout
-	r, w, err := os.Pipe()
-	if err != nil {
-		t.Fatalf("Failed to create pipe: %v", err)
+	if !strings.Contains(buf.String(), "No Git repositories with uncommitted changes found.") {
+		t.Errorf("Unexpected output for empty repos: %q", buf.String())
 	}
-	os.Stdout = w
 
-	err = FormatRepositories(repos, FormatOptions{})
+	// Test case 2: Text format
+	buf.Reset()
+	err = Format(&buf, r, FormatOptions{})
 	if err != nil {
-		t.Errorf("FormatRepositories failed: %v", err)
+		t.Errorf("Format failed: %v", err)
 	}
-
-	// Restore stdout
-	w.Close()
-	os.Stdout = oldStdout
-
-	// Read output
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
 	output := buf.String()
 
 	// Check output contains expected content
 	expectedStrings := []string{
-		"Found 2 Git repositories",
+		"Found 2 Git repositories with uncommitted changes:",
 		"/path/to/repo1",
 		"modified: file1.txt",
 		"added: file2.txt",
 		"/path/to/repo2",
 		"deleted: old_file.txt",
+		"stash@{0}: WIP on main: 1234abc subject (2 hours ago)",
 	}
 
 	for _, s := range expectedStrings {
@@ -72,26 +79,22 @@ func TestFormatRepositories(t *testing.T) {
 		}
 	}
 
-	// Test case 3: JSON format
-	oldStdout = os.Stdout
-	r, w, err = os.Pipe()
+	// Test case 3: Verbose text format
+	buf.Reset()
+	err = Format(&buf, r, FormatOptions{Verbose: true})
 	if err != nil {
-		t.Fatalf("Failed to create pipe: %v", err)
+		t.Errorf("Format failed: %v", err)
 	}
-	os.Stdout = w
-
-	err = FormatRepositories(repos, FormatOptions{JSON: true})
-	if err != nil {
-		t.Errorf("FormatRepositories failed: %v", err)
+	if !strings.Contains(buf.String(), "Found 3 Git repositories in 1.5s") {
+		t.Errorf("Expected verbose output to contain the scan summary, got %q", buf.String())
 	}
 
-	// Restore stdout
-	w.Close()
-	os.Stdout = oldStdout
-
-	// Read output
+	// Test case 4: JSON format
 	buf.Reset()
-	buf.ReadFrom(r)
+	err = Format(&buf, r, FormatOptions{JSON: true})
+	if err != nil {
+		t.Errorf("Format failed: %v", err)
+	}
 	output = buf.String()
 
 	// Parse JSON output
@@ -123,6 +126,12 @@ func TestFormatRepositories(t *testing.T) {
 	if _, ok := metadata["version"]; !ok {
 		t.Error("Expected metadata to have 'version' field")
 	}
+	if metadata["scanned_repositories"] != float64(3) {
+		t.Errorf("Expected 3 scanned repositories, got %v", metadata["scanned_repositories"])
+	}
+	if metadata["duration_seconds"] != 1.5 {
+		t.Errorf("Expected a duration of 1.5 seconds, got %v", metadata["duration_seconds"])
+	}
 
 	// Check repositories
 	repositories, ok := result["repositories"].([]interface{})
@@ -150,4 +159,199 @@ func TestFormatRepositories(t *testing.T) {
 	if len(changes1) != 2 {
 		t.Errorf("Expected 2 changes, got %d", len(changes1))
 	}
+
+	// Check structured change fields
+	change1, ok := changes1[0].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected change to be an object")
+	}
+	expectedChange := map[string]interface{}{
+		"path":     "file1.txt",
+		"index":    "M",
+		"worktree": ".",
+		"kind":     "modified",
+	}
+	for key, value := range expectedChange {
+		if change1[key] != value {
+			t.Errorf("Expected change %s to be %v, got %v", key, value, change1[key])
+		}
+	}
+	if _, ok := change1["orig_path"]; ok {
+		t.Error("Expected orig_path to be omitted for non-renames")
+	}
+
+	// Check stashes
+	repo2, ok := repositories[1].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected repository to be an object")
+	}
+	stashes, ok := repo2["stashes"].([]interface{})
+	if !ok || len(stashes) != 1 {
+		t.Fatalf("Expected 1 stash, got %v", repo2["stashes"])
+	}
+	stash, _ := stashes[0].(map[string]interface{})
+	if stash["message"] != "WIP on main: 1234abc subject" {
+		t.Errorf("Unexpected stash message %v", stash["message"])
+	}
+	if age, _ := stash["age_seconds"].(float64); age < 7200 || age > 7300 {
+		t.Errorf("Expected stash age of about 7200 seconds, got %v", stash["age_seconds"])
+	}
+
+	// Test case 5: Clean repositories reported because of unpushed commits
+	// or stashes; their lists are arrays even when empty
+	buf.Reset()
+	unpushed := &report.Report{
+		Repositories: []*git.Repository{
+			{Path: "/path/to/ahead", UnpushedBranches: []git.UnpushedBranch{{Name: "main", Commits: 1}}},
+			{Path: "/path/to/stashed", Stashes: []git.Stash{{Index: 0, Message: "WIP"}}},
+		},
+		StartTime: scanTime,
+	}
+	if err := Format(&buf, unpushed, FormatOptions{}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Found 2 Git repositories that need attention:") {
+		t.Errorf("Expected a heading without uncommitted changes, got %q", buf.String())
+	}
+	buf.Reset()
+	if err := Format(&buf, unpushed, FormatOptions{JSON: true}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	var lists struct {
+		Repositories []map[string]json.RawMessage `json:"repositories"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &lists); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	for _, repo := range lists.Repositories {
+		for _, key := range []string{"changes", "unpushed_branches", "stashes"} {
+			if !strings.HasPrefix(string(repo[key]), "[") {
+				t.Errorf("%s: expected %s to be an array, got %s", repo["path"], key, repo[key])
+			}
+		}
+	}
+}
+
+func TestFormatProblems(t *testing.T) {
+	r := &report.Report{
+		Warnings: []scanner.Warning{
+			{Path: "/path/to/locked", Err: errors.New("permission denied")},
+		},
+		Errors: []*report.RepoError{
+			{Path: "/path/to/broken", Err: errors.New("exit status 128")},
+		},
+	}
+
+	// Test case 1: Text footer is printed even without repositories
+	var buf bytes.Buffer
+	if err := Format(&buf, r, FormatOptions{}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	output := buf.String()
+	for _, s := range []string{
+		"No Git repositories",
+		"Skipped 1 unreadable paths",
+		"/path/to/locked: permission denied",
+		"Failed to check 1 Git repositories",
+		"/path/to/broken: exit status 128",
+	} {
+		if !strings.Contains(output, s) {
+			t.Errorf("Expected output to contain %q, got %q", s, output)
+		}
+	}
+
+	// Test case 2: JSON output has warnings and errors arrays
+	buf.Reset()
+	if err := Format(&buf, r, FormatOptions{JSON: true}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	type problem struct {
+		Path  string `json:"path"`
+		Error string `json:"error"`
+	}
+	var result struct {
+		Repositories []interface{} `json:"repositories"`
+		Warnings     []problem     `json:"warnings"`
+		Errors       []problem     `json:"errors"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0] != (problem{"/path/to/locked", "permission denied"}) {
+		t.Errorf("Unexpected warnings: %+v", result.Warnings)
+	}
+	if len(result.Errors) != 1 || result.Errors[0] != (problem{"/path/to/broken", "exit status 128"}) {
+		t.Errorf("Unexpected errors: %+v", result.Errors)
+	}
+
+	// Test case 3: Timeouts and interrupted scans are flagged
+	r.Errors = append(r.Errors, &report.RepoError{Path: "/path/to/slow", Err: errors.New("timed out after 1s"), TimedOut: true})
+	r.Interrupted = true
+	buf.Reset()
+	if err := Format(&buf, r, FormatOptions{}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Scan interrupted") {
+		t.Errorf("Expected the text output to mention the interruption, got %q", buf.String())
+	}
+	buf.Reset()
+	if err := Format(&buf, r, FormatOptions{JSON: true}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	var flagged struct {
+		Errors []struct {
+			Path     string `json:"path"`
+			TimedOut bool   `json:"timed_out"`
+		} `json:"errors"`
+		Metadata struct {
+			Interrupted bool `json:"interrupted"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &flagged); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if len(flagged.Errors) != 2 || flagged.Errors[0].TimedOut || !flagged.Errors[1].TimedOut {
+		t.Errorf("Expected only the second error to be timed out: %+v", flagged.Errors)
+	}
+	if !flagged.Metadata.Interrupted {
+		t.Error("Expected metadata.interrupted to be true")
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := map[time.Duration]string{
+		10 * time.Second:      "just now",
+		time.Minute:           "1 minute ago",
+		5 * time.Minute:       "5 minutes ago",
+		3 * time.Hour:         "3 hours ago",
+		49 * time.Hour:        "2 days ago",
+		65 * 24 * time.Hour:   "2 months ago",
+		400 * 24 * time.Hour:  "1 year ago",
+		1000 * 24 * time.Hour: "2 years ago",
+	}
+	for d, want := range tests {
+		if got := formatAge(d); got != want {
+			t.Errorf("formatAge(%v): expected %q, got %q", d, want, got)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		n    int
+		noun string
+		want string
+	}{
+		{1, "commit", "1 commit"},
+		{2, "commit", "2 commits"},
+		{0, "stash", "0 stashes"},
+		{3, "branch", "3 branches"},
+		{2, "repository", "2 repositories"},
+		{2, "day", "2 days"},
+	}
+	for _, tt := range tests {
+		if got := plural(tt.n, tt.noun); got != tt.want {
+			t.Errorf("plural(%d, %q): expected %q, got %q", tt.n, tt.noun, tt.want, got)
+		}
+	}
 }
@@ -0,0 +1,166 @@
+package formatter
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("html", FormatterFunc(formatHTML))
+}
+
+// htmlTemplate is a single static page with inline styles and scripts, so
+// that the report can be published as one file
+//
+//go:embed html.tmpl
+var htmlTemplate string
+
+// htmlPage is the parsed page template
+var htmlPage = template.Must(template.New("html").Funcs(template.FuncMap{
+	"plural": plural,
+}).Parse(htmlTemplate))
+
+// htmlReport is the data of the html page
+type htmlReport struct {
+	Root         string
+	ScanTime     time.Time
+	Duration     time.Duration
+	Total        int
+	Interrupted  bool
+	Version      string
+	Repositories []htmlRepository
+	Warnings     []jsonProblem
+	Errors       []jsonProblem
+}
+
+// htmlRepository is a row of the repository table
+type htmlRepository struct {
+	Path     string
+	Branch   string
+	Upstream string
+	State    git.State
+	// States are the filters the repository matches, separated by spaces
+	States                      string
+	Staged, Unstaged, Untracked int
+	Ahead, Behind               int
+	Groups                      []htmlChangeGroup
+	Unpushed                    []git.UnpushedBranch
+	Stashes                     []git.Stash
+	LastCommitUnix              int64
+	LastCommitAge               string
+}
+
+// htmlChangeGroup is a list of changed files of a repository
+type htmlChangeGroup struct {
+	Title   string
+	Changes []htmlChange
+}
+
+// htmlChange is a changed file with the class it is styled with
+type htmlChange struct {
+	git.Change
+	Class string
+}
+
+// newHTMLReport converts a report to the data of the html page
+func newHTMLReport(r *report.Report) htmlReport {
+	warnings, errs := newJSONProblems(r)
+	page := htmlReport{
+		Root:        r.Root,
+		ScanTime:    reportTime(r),
+		Duration:    r.Duration.Round(time.Millisecond),
+		Total:       r.Total(),
+		Interrupted: r.Interrupted,
+		Version:     Version,
+		Warnings:    warnings,
+		Errors:      errs,
+	}
+
+	for _, repo := range r.Repositories {
+		c := countChanges(repo.Changes)
+		row := htmlRepository{
+			Path:      relPath(r.Root, repo.Path),
+			Branch:    repo.Branch,
+			Upstream:  repo.Upstream,
+			State:     repo.State,
+			States:    strings.Join(htmlStates(repo), " "),
+			Staged:    c.staged,
+			Unstaged:  c.unstaged,
+			Untracked: c.untracked,
+			Ahead:     repo.Ahead,
+			Behind:    repo.Behind,
+			Unpushed:  repo.UnpushedBranches,
+			Stashes:   repo.Stashes,
+		}
+		row.LastCommitAge = "-"
+		if row.Branch == "" {
+			row.Branch = "(" + string(git.StateDetached) + ")"
+		}
+		if !repo.LastCommit.IsZero() {
+			row.LastCommitUnix = repo.LastCommit.Unix()
+			row.LastCommitAge = formatAge(reportTime(r).Sub(repo.LastCommit))
+		}
+
+		for _, group := range groupChanges(repo.Changes) {
+			if len(group.changes) == 0 {
+				continue
+			}
+			g := htmlChangeGroup{Title: group.title}
+			for _, change := range group.changes {
+				g.Changes = append(g.Changes, htmlChange{Change: change, Class: htmlChangeClass(change)})
+			}
+			row.Groups = append(row.Groups, g)
+		}
+		page.Repositories = append(page.Repositories, row)
+	}
+
+	return page
+}
+
+// htmlStates returns the filters of the page a repository matches
+func htmlStates(repo *git.Repository) []string {
+	var states []string
+	if repo.IsDirty() {
+		states = append(states, "dirty")
+	}
+	if repo.HasUnpushed() {
+		states = append(states, "unpushed")
+	}
+	if repo.HasStashes() {
+		states = append(states, "stashed")
+	}
+	for _, change := range repo.Changes {
+		if change.Kind == git.ChangeUnmerged {
+			states = append(states, "conflicted")
+			break
+		}
+	}
+	return states
+}
+
+// htmlChangeClass returns the CSS class of a change, matching the colors of
+// terminal output
+func htmlChangeClass(change git.Change) string {
+	switch changeStyle(change) {
+	case styleConflict:
+		return "conflict"
+	case styleUntracked:
+		return "untracked"
+	case styleUnstaged:
+		return "unstaged"
+	case styleStaged:
+		return "staged"
+	}
+	return ""
+}
+
+// formatHTML formats the report as a standalone HTML page
+func formatHTML(w io.Writer, r *report.Report, opts FormatOptions) error {
+	return htmlPage.Execute(w, newHTMLReport(r))
+}
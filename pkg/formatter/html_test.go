@@ -0,0 +1,79 @@
+package formatter
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func TestFormatHTML(t *testing.T) {
+	r := &report.Report{
+		Root: "/src",
+		Repositories: []*git.Repository{
+			{
+				Path:       "/src/app",
+				Branch:     "main",
+				LastCommit: scanTime.Add(-2 * time.Hour),
+				Changes: []git.Change{
+					git.NewChange(git.StatusAdded, git.StatusUnmodified, "<script>alert(1)</script>.go", ""),
+					git.NewChange(git.StatusUnmerged, git.StatusUnmerged, "conflict.go", ""),
+				},
+				State: git.StateMerging,
+			},
+			{
+				Path:             "/src/lib",
+				Branch:           "dev",
+				UnpushedBranches: []git.UnpushedBranch{{Name: "dev", Commits: 1}},
+				Stashes:          []git.Stash{{Index: 0, Message: "WIP"}},
+			},
+		},
+		Errors:    []*report.RepoError{{Path: "/src/broken", Err: errors.New("exit status 128")}},
+		StartTime: scanTime,
+		Duration:  1500 * time.Millisecond,
+	}
+
+	var buf bytes.Buffer
+	if err := Format(&buf, r, FormatOptions{Format: "html"}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	output := buf.String()
+
+	for _, s := range []string{
+		"<!DOCTYPE html>",
+		"<title>gus report: /src</title>",
+		`<time datetime="2024-03-20T10:30:00Z">`,
+		"<dd>1.5s</dd>",
+		"<dd>2 reported of 3 scanned</dd>",
+		`<tr data-states="dirty conflicted">`,
+		`<tr data-states="unpushed stashed">`,
+		`<summary>app<span class="tag">merging</span></summary>`,
+		`<li class="staged">&lt;script&gt;alert(1)&lt;/script&gt;.go (added)</li>`,
+		`<li class="conflict">conflict.go (unmerged)</li>`,
+		"<li>dev (1 commit)</li>",
+		"<li>stash@{0}: WIP</li>",
+		`<td class="num" data-value="0">-</td>`,
+		"2 hours ago",
+		"<li>/src/broken: exit status 128</li>",
+		`<input type="checkbox" value="conflicted">`,
+		"<script>",
+	} {
+		if !strings.Contains(output, s) {
+			t.Errorf("Expected output to contain %q", s)
+		}
+	}
+
+	// The page must not load anything from elsewhere
+	for _, s := range []string{"<script src", "<link", "http://", "https://"} {
+		if strings.Contains(output, s) {
+			t.Errorf("Expected a standalone page, found %q", s)
+		}
+	}
+	if strings.Contains(output, "<script>alert") {
+		t.Error("Expected file names to be escaped")
+	}
+}
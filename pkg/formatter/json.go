@@ -0,0 +1,157 @@
+package formatter
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("json", FormatterFunc(formatJSON))
+}
+
+// jsonOutput is the document written by the JSON formatter
+type jsonOutput struct {
+	Repositories []jsonRepository `json:"repositories"`
+	Warnings     []jsonProblem    `json:"warnings"`
+	Errors       []jsonProblem    `json:"errors"`
+	Metadata     jsonMetadata     `json:"metadata"`
+}
+
+// jsonRepository is a reported repository
+type jsonRepository struct {
+	Path             string               `json:"path"`
+	Kind             git.Kind             `json:"kind,omitempty"`
+	Parent           string               `json:"parent,omitempty"`
+	State            git.State            `json:"state,omitempty"`
+	Changes          []git.Change         `json:"changes"`
+	Branch           string               `json:"branch,omitempty"`
+	Upstream         string               `json:"upstream,omitempty"`
+	Ahead            int                  `json:"ahead"`
+	Behind           int                  `json:"behind"`
+	UnpushedBranches []git.UnpushedBranch `json:"unpushed_branches"`
+	Stashes          []jsonStash          `json:"stashes"`
+	LastCommit       *time.Time           `json:"last_commit,omitempty"`
+	ScanTime         time.Time            `json:"scan_time"`
+	TotalRepos       int                  `json:"total_repositories,omitempty"`
+}
+
+// jsonStash is a stash entry with its age at the time of the scan
+type jsonStash struct {
+	git.Stash
+	AgeSeconds int64 `json:"age_seconds"`
+}
+
+// jsonProblem is a skipped path or a repository that could not be checked
+type jsonProblem struct {
+	Path     string `json:"path"`
+	Error    string `json:"error"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+}
+
+// jsonMetadata describes the scan itself
+type jsonMetadata struct {
+	Root            string    `json:"root"`
+	ScanTime        time.Time `json:"scan_time"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	TotalRepos      int       `json:"total_repositories"`
+	ScannedRepos    int       `json:"scanned_repositories"`
+	Interrupted     bool      `json:"interrupted"`
+	Version         string    `json:"version"`
+}
+
+// newJSONOutput converts a report to the JSON document
+func newJSONOutput(r *report.Report) jsonOutput {
+	repos := make([]jsonRepository, len(r.Repositories))
+	for i, repo := range r.Repositories {
+		repos[i] = newJSONRepository(repo, r)
+	}
+
+	warnings, errs := newJSONProblems(r)
+	return jsonOutput{
+		Repositories: repos,
+		Warnings:     warnings,
+		Errors:       errs,
+		Metadata:     newJSONMetadata(r),
+	}
+}
+
+// newJSONProblems converts the warnings and errors of a report
+func newJSONProblems(r *report.Report) (warnings, errs []jsonProblem) {
+	warnings = make([]jsonProblem, len(r.Warnings))
+	for i, w := range r.Warnings {
+		warnings[i] = jsonProblem{Path: w.Path, Error: w.Err.Error()}
+	}
+
+	errs = make([]jsonProblem, len(r.Errors))
+	for i, e := range r.Errors {
+		errs[i] = jsonProblem{Path: e.Path, Error: e.Err.Error(), TimedOut: e.TimedOut}
+	}
+	return warnings, errs
+}
+
+// newJSONMetadata describes the scan of a report
+func newJSONMetadata(r *report.Report) jsonMetadata {
+	return jsonMetadata{
+		Root:            r.Root,
+		ScanTime:        reportTime(r),
+		DurationSeconds: r.Duration.Seconds(),
+		TotalRepos:      len(r.Repositories),
+		ScannedRepos:    r.Total(),
+		Interrupted:     r.Interrupted,
+		Version:         Version,
+	}
+}
+
+// newJSONRepository converts a repository of the report to its JSON form
+func newJSONRepository(repo *git.Repository, r *report.Report) jsonRepository {
+	stashes := make([]jsonStash, len(repo.Stashes))
+	for i, stash := range repo.Stashes {
+		stashes[i] = jsonStash{
+			Stash:      stash,
+			AgeSeconds: int64(stash.Age(reportTime(r)).Seconds()),
+		}
+	}
+
+	var lastCommit *time.Time
+	if !repo.LastCommit.IsZero() {
+		lastCommit = &repo.LastCommit
+	}
+
+	// Lists are always arrays, so that consumers need no null checks
+	changes := repo.Changes
+	if changes == nil {
+		changes = []git.Change{}
+	}
+	unpushed := repo.UnpushedBranches
+	if unpushed == nil {
+		unpushed = []git.UnpushedBranch{}
+	}
+
+	return jsonRepository{
+		Path:             repo.Path,
+		Kind:             repo.Kind,
+		Parent:           repo.Parent,
+		State:            repo.State,
+		Changes:          changes,
+		Branch:           repo.Branch,
+		Upstream:         repo.Upstream,
+		Ahead:            repo.Ahead,
+		Behind:           repo.Behind,
+		UnpushedBranches: unpushed,
+		Stashes:          stashes,
+		LastCommit:       lastCommit,
+		ScanTime:         reportTime(r),
+		TotalRepos:       len(r.Repositories),
+	}
+}
+
+// formatJSON formats the report as JSON
+func formatJSON(w io.Writer, r *report.Report, opts FormatOptions) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(newJSONOutput(r))
+}
@@ -0,0 +1,182 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("junit", FormatterFunc(formatJUnit))
+}
+
+// junitSuiteName is the name of the single test suite
+const junitSuiteName = "gus"
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds one test case per repository
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+// junitProperty is a name/value pair describing the scan
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase is a repository; it fails when the repository is reported
+// and errors when it could not be checked
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+// junitProblem is the failure or error of a test case
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// formatJUnit formats the report as JUnit XML for CI test report viewers
+func formatJUnit(w io.Writer, r *report.Report, opts FormatOptions) error {
+	suite := junitTestSuite{
+		Name:      junitSuiteName,
+		Time:      fmt.Sprintf("%.3f", r.Duration.Seconds()),
+		Timestamp: reportTime(r).UTC().Format("2006-01-02T15:04:05"),
+		Properties: []junitProperty{
+			{Name: "root", Value: r.Root},
+			{Name: "interrupted", Value: fmt.Sprint(r.Interrupted)},
+			{Name: "version", Value: Version},
+		},
+	}
+
+	for _, repo := range r.Repositories {
+		details := repoDetails(repo, r)
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      relPath(r.Root, repo.Path),
+			ClassName: junitSuiteName,
+			Failure: &junitProblem{
+				Message: repoSummary(repo),
+				Type:    "uncommitted",
+				Body:    strings.Join(details, "\n"),
+			},
+		})
+		suite.Failures++
+	}
+	for _, repo := range r.Clean {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      relPath(r.Root, repo.Path),
+			ClassName: junitSuiteName,
+		})
+	}
+	for _, e := range r.Errors {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      relPath(r.Root, e.Path),
+			ClassName: junitSuiteName,
+			Error: &junitProblem{
+				Message: e.Err.Error(),
+				Type:    junitErrorType(e),
+			},
+		})
+		suite.Errors++
+	}
+	sort.SliceStable(suite.TestCases, func(i, j int) bool {
+		return suite.TestCases[i].Name < suite.TestCases[j].Name
+	})
+	suite.Tests = len(suite.TestCases)
+
+	doc := junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitErrorType tells timeouts apart from other errors
+func junitErrorType(e *report.RepoError) string {
+	if e.TimedOut {
+		return "timeout"
+	}
+	return "error"
+}
+
+// repoSummary describes in one line why a repository is reported
+func repoSummary(repo *git.Repository) string {
+	var parts []string
+	if len(repo.Changes) > 0 {
+		parts = append(parts, plural(len(repo.Changes), "uncommitted change"))
+	}
+	if repo.Ahead > 0 {
+		parts = append(parts, plural(repo.Ahead, "unpushed commit"))
+	}
+	if n := len(repo.UnpushedBranches); n > 0 {
+		parts = append(parts, plural(n, "unpushed branch"))
+	}
+	if n := len(repo.Stashes); n > 0 {
+		parts = append(parts, plural(n, "stash"))
+	}
+	if repo.InProgress() {
+		parts = append(parts, string(repo.State))
+	}
+	if len(parts) == 0 {
+		return "reported"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// repoDetails lists what is reported about a repository, one item per line
+// like the text format
+func repoDetails(repo *git.Repository, r *report.Report) []string {
+	var lines []string
+	if repo.Ahead > 0 || repo.Behind > 0 {
+		lines = append(lines, fmt.Sprintf("branch: %s -> %s (ahead %d, behind %d)", repo.Branch, repo.Upstream, repo.Ahead, repo.Behind))
+	}
+	for _, change := range repo.Changes {
+		lines = append(lines, change.String())
+	}
+	for _, branch := range repo.UnpushedBranches {
+		lines = append(lines, fmt.Sprintf("unpushed: %s (%s)", branch.Name, plural(branch.Commits, "commit")))
+	}
+	for _, stash := range repo.Stashes {
+		lines = append(lines, fmt.Sprintf("%s: %s (%s)", stash.Name(), stash.Message, formatAge(stash.Age(reportTime(r)))))
+	}
+	return lines
+}
@@ -0,0 +1,105 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func TestFormatJUnit(t *testing.T) {
+	r := &report.Report{
+		Root: "/src",
+		Repositories: []*git.Repository{
+			{
+				Path:   "/src/dirty",
+				Branch: "main",
+				Changes: []git.Change{
+					git.NewChange(git.StatusModified, git.StatusUnmodified, "main.go", ""),
+					git.NewChange(git.StatusUntracked, git.StatusUntracked, "a&b.txt", ""),
+				},
+			},
+			{
+				Path:             "/src/ahead",
+				Branch:           "main",
+				Upstream:         "origin/main",
+				Ahead:            2,
+				UnpushedBranches: []git.UnpushedBranch{{Name: "main", Commits: 2}},
+			},
+		},
+		Clean:     []*git.Repository{{Path: "/src/clean"}},
+		Errors:    []*report.RepoError{{Path: "/src/slow", Err: errors.New("timed out after 1s"), TimedOut: true}},
+		StartTime: scanTime,
+		Duration:  2500 * time.Millisecond,
+	}
+
+	var buf bytes.Buffer
+	if err := Format(&buf, r, FormatOptions{Format: "junit"}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("Expected an XML declaration, got %q", buf.String())
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to parse output: %v\n%s", err, buf.String())
+	}
+	if doc.Tests != 4 || doc.Failures != 2 || doc.Errors != 1 || doc.Time != "2.500" {
+		t.Errorf("Unexpected totals: %+v", doc)
+	}
+	if len(doc.Suites) != 1 {
+		t.Fatalf("Expected 1 test suite, got %d", len(doc.Suites))
+	}
+	suite := doc.Suites[0]
+	if suite.Timestamp != "2024-03-20T10:30:00" || suite.Time != "2.500" {
+		t.Errorf("Expected the scan timing on the suite, got %q and %q", suite.Timestamp, suite.Time)
+	}
+
+	// Test cases are sorted by path
+	cases := map[string]junitTestCase{}
+	var names []string
+	for _, tc := range suite.TestCases {
+		cases[tc.Name] = tc
+		names = append(names, tc.Name)
+	}
+	if strings.Join(names, ",") != "ahead,clean,dirty,slow" {
+		t.Errorf("Unexpected test cases: %v", names)
+	}
+
+	// Test case 1: Clean repositories pass
+	if tc := cases["clean"]; tc.Failure != nil || tc.Error != nil {
+		t.Errorf("Expected the clean repository to pass: %+v", tc)
+	}
+
+	// Test case 2: Dirty repositories fail with their changes
+	dirty := cases["dirty"].Failure
+	if dirty == nil {
+		t.Fatal("Expected the dirty repository to fail")
+	}
+	if dirty.Message != "2 uncommitted changes" {
+		t.Errorf("Unexpected failure message %q", dirty.Message)
+	}
+	if dirty.Body != "modified: main.go\nuntracked: a&b.txt" {
+		t.Errorf("Unexpected failure body %q", dirty.Body)
+	}
+
+	// Test case 3: Unpushed repositories fail too
+	ahead := cases["ahead"].Failure
+	if ahead == nil || ahead.Message != "2 unpushed commits, 1 unpushed branch" {
+		t.Errorf("Unexpected failure for the unpushed repository: %+v", ahead)
+	}
+	if ahead != nil && !strings.Contains(ahead.Body, "branch: main -> origin/main (ahead 2, behind 0)") {
+		t.Errorf("Expected the failure body to show the branch, got %q", ahead.Body)
+	}
+
+	// Test case 4: Repositories that could not be checked are errors
+	if e := cases["slow"].Error; e == nil || e.Type != "timeout" {
+		t.Errorf("Expected a timeout error, got %+v", e)
+	}
+}
@@ -0,0 +1,190 @@
+package formatter
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("markdown", FormatterFunc(formatMarkdown))
+}
+
+// markdownEscaper escapes the characters that have a meaning inside Markdown
+// text, and flattens line breaks which would end a list item or table row
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`|`, `\|`, `~`, `\~`, `<`, `&lt;`, `>`, `&gt;`, `&`, `&amp;`,
+	"\r", " ", "\n", " ",
+)
+
+// escapeMarkdown makes text appear literally in Markdown, also where it
+// starts a block such as a list item
+func escapeMarkdown(s string) string {
+	s = markdownEscaper.Replace(s)
+
+	// Block markers: headings, list items and setext underlines
+	if s != "" && strings.ContainsRune("#-+=", rune(s[0])) {
+		return `\` + s
+	}
+	// Ordered list items
+	digits := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if digits > 0 && (s[digits] == '.' || s[digits] == ')') {
+		return s[:digits] + `\` + s[digits:]
+	}
+	return s
+}
+
+// changeGroup is a titled list of changes of a repository
+type changeGroup struct {
+	title   string
+	changes []git.Change
+}
+
+// groupChanges sorts the changes of a repository into staged, unstaged and
+// untracked files. A file with both staged and unstaged changes is in both
+// groups; conflicts are unstaged.
+func groupChanges(changes []git.Change) []changeGroup {
+	groups := []changeGroup{{title: "Staged"}, {title: "Unstaged"}, {title: "Untracked"}}
+	for _, change := range changes {
+		switch {
+		case change.Kind == git.ChangeUntracked:
+			groups[2].changes = append(groups[2].changes, change)
+			continue
+		case change.Kind == git.ChangeUnmerged:
+			groups[1].changes = append(groups[1].changes, change)
+			continue
+		}
+		if change.Staged() {
+			groups[0].changes = append(groups[0].changes, change)
+		}
+		if change.Unstaged() {
+			groups[1].changes = append(groups[1].changes, change)
+		}
+	}
+	return groups
+}
+
+// formatMarkdown formats the report as Markdown: a summary table followed by
+// a collapsible section per repository
+func formatMarkdown(w io.Writer, r *report.Report, opts FormatOptions) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "## Uncommitted changes")
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "Scanned %s on %s. Repositories reported: %d of %d.\n",
+		escapeMarkdown(r.Root), reportTime(r).UTC().Format("2006-01-02 15:04 MST"),
+		len(r.Repositories), r.Total())
+	fmt.Fprintln(bw)
+
+	if len(r.Repositories) == 0 {
+		fmt.Fprintln(bw, "No Git repositories with uncommitted changes found.")
+	} else {
+		fmt.Fprintln(bw, "| Repository | Branch | Staged | Unstaged | Untracked | Ahead | Behind | Last commit |")
+		fmt.Fprintln(bw, "|---|---|--:|--:|--:|--:|--:|---|")
+		for _, repo := range r.Repositories {
+			c := countChanges(repo.Changes)
+			lastCommit := "-"
+			if !repo.LastCommit.IsZero() {
+				lastCommit = formatAge(reportTime(r).Sub(repo.LastCommit))
+			}
+			fmt.Fprintf(bw, "| %s | %s | %d | %d | %d | %d | %d | %s |\n",
+				escapeMarkdown(relPath(r.Root, repo.Path)), escapeMarkdown(repo.Branch),
+				c.staged, c.unstaged, c.untracked, repo.Ahead, repo.Behind, lastCommit)
+		}
+
+		for _, repo := range r.Repositories {
+			formatMarkdownRepository(bw, repo, r)
+		}
+	}
+
+	formatMarkdownProblems(bw, r)
+	return bw.Flush()
+}
+
+// formatMarkdownRepository writes the collapsible section of a repository
+func formatMarkdownRepository(w io.Writer, repo *git.Repository, r *report.Report) {
+	// The summary is HTML, in which Markdown is not rendered
+	summary := "<code>" + html.EscapeString(relPath(r.Root, repo.Path)) + "</code>"
+	if repo.Branch != "" {
+		summary += " on " + html.EscapeString(repo.Branch)
+	}
+	if repo.InProgress() {
+		summary += " [" + string(repo.State) + "]"
+	}
+	summary += " (" + plural(len(repo.Changes), "change") + ")"
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "<details>")
+	fmt.Fprintf(w, "<summary>%s</summary>\n", summary)
+
+	for _, group := range groupChanges(repo.Changes) {
+		if len(group.changes) == 0 {
+			continue
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "**%s**\n\n", group.title)
+		for _, change := range group.changes {
+			fmt.Fprintf(w, "- %s\n", markdownChange(change))
+		}
+	}
+
+	var extras []string
+	if repo.Ahead > 0 || repo.Behind > 0 {
+		extras = append(extras, fmt.Sprintf("%s → %s: ahead %d, behind %d",
+			escapeMarkdown(repo.Branch), escapeMarkdown(repo.Upstream), repo.Ahead, repo.Behind))
+	}
+	for _, branch := range repo.UnpushedBranches {
+		extras = append(extras, fmt.Sprintf("unpushed: %s (%s)", escapeMarkdown(branch.Name), plural(branch.Commits, "commit")))
+	}
+	for _, stash := range repo.Stashes {
+		extras = append(extras, fmt.Sprintf("%s: %s (%s)",
+			escapeMarkdown(stash.Name()), escapeMarkdown(stash.Message), formatAge(stash.Age(reportTime(r)))))
+	}
+	if len(extras) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "**Other**")
+		fmt.Fprintln(w)
+		for _, extra := range extras {
+			fmt.Fprintf(w, "- %s\n", extra)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "</details>")
+}
+
+// markdownChange describes a changed file as a list item
+func markdownChange(change git.Change) string {
+	text := escapeMarkdown(change.Path)
+	if change.OrigPath != "" {
+		text = escapeMarkdown(change.OrigPath) + " → " + text
+	}
+	return text + " (" + change.Kind.String() + ")"
+}
+
+// formatMarkdownProblems lists the skipped paths and the repositories that
+// could not be checked
+func formatMarkdownProblems(w io.Writer, r *report.Report) {
+	if len(r.Warnings) == 0 && len(r.Errors) == 0 && !r.Interrupted {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "### Problems")
+	fmt.Fprintln(w)
+	for _, warning := range r.Warnings {
+		fmt.Fprintf(w, "- Skipped %s: %s\n", escapeMarkdown(relPath(r.Root, warning.Path)), escapeMarkdown(warning.Err.Error()))
+	}
+	for _, e := range r.Errors {
+		fmt.Fprintf(w, "- Failed to check %s: %s\n", escapeMarkdown(relPath(r.Root, e.Path)), escapeMarkdown(e.Err.Error()))
+	}
+	if r.Interrupted {
+		fmt.Fprintln(w, "- The scan was interrupted, results are incomplete.")
+	}
+}
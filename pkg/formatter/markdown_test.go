@@ -0,0 +1,79 @@
+package formatter
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := map[string]string{
+		"main.go":          "main.go",
+		"my_file*.md":      `my\_file\*.md`,
+		"[link](x)":        `\[link\](x)`,
+		"a|b":              `a\|b`,
+		"<script>&":        "&lt;script&gt;&amp;",
+		"`code`":           "\\`code\\`",
+		"line\nbreak":      "line break",
+		"# heading":        `\# heading`,
+		"- item":           `\- item`,
+		"2024. report.txt": `2024\. report.txt`,
+		"2024-report.txt":  "2024-report.txt",
+		`back\slash`:       `back\\slash`,
+	}
+	for in, want := range tests {
+		if got := escapeMarkdown(in); got != want {
+			t.Errorf("escapeMarkdown(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestFormatMarkdown(t *testing.T) {
+	r := &report.Report{
+		Root: "/src",
+		Repositories: []*git.Repository{
+			{
+				Path:   "/src/app",
+				Branch: "main",
+				Changes: []git.Change{
+					git.NewChange(git.StatusModified, git.StatusModified, "main.go", ""),
+					git.NewChange(git.StatusRenamed, git.StatusUnmodified, "new_name.go", "old_name.go"),
+					git.NewChange(git.StatusUntracked, git.StatusUntracked, "notes*.txt", ""),
+				},
+			},
+			{Path: "/src/<lib>", Branch: "dev"},
+		},
+		Errors:    []*report.RepoError{{Path: "/src/broken", Err: errors.New("exit status 128")}},
+		StartTime: scanTime,
+	}
+
+	var buf bytes.Buffer
+	if err := Format(&buf, r, FormatOptions{Format: "markdown"}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	output := buf.String()
+
+	for _, s := range []string{
+		"Scanned /src on 2024-03-20 10:30 UTC. Repositories reported: 2 of 3.",
+		"| Repository | Branch | Staged | Unstaged | Untracked | Ahead | Behind | Last commit |",
+		"| app | main | 2 | 1 | 1 | 0 | 0 | - |",
+		"| &lt;lib&gt; | dev | 0 | 0 | 0 | 0 | 0 | - |",
+		"<summary><code>app</code> on main (3 changes)</summary>",
+		"<summary><code>&lt;lib&gt;</code> on dev (0 changes)</summary>",
+		"**Staged**\n\n- main.go (modified)\n- old\\_name.go → new\\_name.go (renamed)\n",
+		"**Unstaged**\n\n- main.go (modified)\n",
+		"**Untracked**\n\n- notes\\*.txt (untracked)\n",
+		"- Failed to check broken: exit status 128",
+	} {
+		if !strings.Contains(output, s) {
+			t.Errorf("Expected output to contain %q, got:\n%s", s, output)
+		}
+	}
+	if strings.Count(output, "<details>") != 2 || strings.Count(output, "</details>") != 2 {
+		t.Errorf("Expected a collapsible section per repository, got:\n%s", output)
+	}
+}
@@ -0,0 +1,71 @@
+package formatter
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("ndjson", ndjsonFormatter{})
+}
+
+// Record types of the ndjson format
+const (
+	ndjsonTypeRepository = "repository"
+	ndjsonTypeSummary    = "summary"
+)
+
+// ndjsonRepository is a line describing one repository, with the fields of
+// a repository of the JSON format
+type ndjsonRepository struct {
+	Type string `json:"type"`
+	jsonRepository
+}
+
+// ndjsonSummary is the last line, with the fields of the JSON format
+// other than the repositories
+type ndjsonSummary struct {
+	Type     string        `json:"type"`
+	Warnings []jsonProblem `json:"warnings"`
+	Errors   []jsonProblem `json:"errors"`
+	Metadata jsonMetadata  `json:"metadata"`
+}
+
+// ndjsonFormatter writes newline-delimited JSON: one record per repository
+// followed by a summary record
+type ndjsonFormatter struct{}
+
+// Format writes the repositories of a complete report and its summary
+func (f ndjsonFormatter) Format(w io.Writer, r *report.Report, opts FormatOptions) error {
+	for _, repo := range r.Repositories {
+		if err := f.FormatRepository(w, repo, r, opts); err != nil {
+			return err
+		}
+	}
+	return f.FormatSummary(w, r, opts)
+}
+
+// FormatRepository writes the record of one repository
+func (ndjsonFormatter) FormatRepository(w io.Writer, repo *git.Repository, r *report.Report, opts FormatOptions) error {
+	record := ndjsonRepository{
+		Type:           ndjsonTypeRepository,
+		jsonRepository: newJSONRepository(repo, r),
+	}
+	// The total is not known while streaming; it is in the summary
+	record.TotalRepos = 0
+	return json.NewEncoder(w).Encode(record)
+}
+
+// FormatSummary writes the summary record
+func (ndjsonFormatter) FormatSummary(w io.Writer, r *report.Report, opts FormatOptions) error {
+	warnings, errs := newJSONProblems(r)
+	return json.NewEncoder(w).Encode(ndjsonSummary{
+		Type:     ndjsonTypeSummary,
+		Warnings: warnings,
+		Errors:   errs,
+		Metadata: newJSONMetadata(r),
+	})
+}
@@ -0,0 +1,81 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func TestFormatNDJSON(t *testing.T) {
+	r := &report.Report{
+		Root: "/src",
+		Repositories: []*git.Repository{
+			{Path: "/src/a", Changes: []git.Change{
+				git.NewChange(git.StatusModified, git.StatusUnmodified, "main.go", ""),
+			}},
+			{Path: "/src/b", Branch: "main", Ahead: 1},
+		},
+		Clean:     []*git.Repository{{Path: "/src/c"}},
+		StartTime: scanTime,
+	}
+
+	var buf bytes.Buffer
+	if err := Format(&buf, r, FormatOptions{Format: "ndjson"}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	// Test case 1: Repository records have the fields of the JSON format
+	var repo map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &repo); err != nil {
+		t.Fatalf("Failed to parse %q: %v", lines[0], err)
+	}
+	if repo["type"] != "repository" || repo["path"] != "/src/a" {
+		t.Errorf("Unexpected repository record: %v", repo)
+	}
+	changes, _ := repo["changes"].([]interface{})
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 change, got %v", repo["changes"])
+	}
+	if change, _ := changes[0].(map[string]interface{}); change["kind"] != "modified" || change["index"] != "M" {
+		t.Errorf("Unexpected change: %v", changes[0])
+	}
+	if _, ok := repo["total_repositories"]; ok {
+		t.Error("Expected repository records to leave the total to the summary")
+	}
+
+	// Test case 2: The summary comes last
+	var summary struct {
+		Type     string        `json:"type"`
+		Warnings []interface{} `json:"warnings"`
+		Metadata struct {
+			Root         string `json:"root"`
+			TotalRepos   int    `json:"total_repositories"`
+			ScannedRepos int    `json:"scanned_repositories"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &summary); err != nil {
+		t.Fatalf("Failed to parse %q: %v", lines[2], err)
+	}
+	if summary.Type != "summary" || summary.Metadata.Root != "/src" || summary.Metadata.TotalRepos != 2 || summary.Metadata.ScannedRepos != 3 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if summary.Warnings == nil {
+		t.Error("Expected warnings to be an empty array")
+	}
+
+	// Test case 3: ndjson streams, json does not
+	if _, ok := Streaming(FormatOptions{Format: "ndjson"}); !ok {
+		t.Error("Expected ndjson to be a stream format")
+	}
+	if _, ok := Streaming(FormatOptions{Format: "json"}); ok {
+		t.Error("Expected json not to be a stream format")
+	}
+}
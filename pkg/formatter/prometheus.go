@@ -0,0 +1,185 @@
+package formatter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("prometheus", FormatterFunc(formatPrometheus))
+}
+
+// RepoLabel tells how repositories are labelled in the prometheus format
+type RepoLabel string
+
+const (
+	// RepoLabelPath labels repositories with their absolute path
+	RepoLabelPath RepoLabel = "path"
+	// RepoLabelRelPath labels repositories with their path relative to the
+	// scanned directory
+	RepoLabelRelPath RepoLabel = "relpath"
+	// RepoLabelNone leaves out the per-repository metrics
+	RepoLabelNone RepoLabel = "none"
+)
+
+// ParseRepoLabel converts the value of the --metrics-repo-label flag
+func ParseRepoLabel(s string) (RepoLabel, error) {
+	switch label := RepoLabel(s); label {
+	case RepoLabelPath, RepoLabelRelPath, RepoLabelNone:
+		return label, nil
+	}
+	return "", fmt.Errorf("invalid repository label %q (expected path, relpath or none)", s)
+}
+
+// labelEscaper escapes label values as the Prometheus text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricWriter writes metrics in the Prometheus text exposition format
+type metricWriter struct {
+	w io.Writer
+}
+
+// header writes the HELP and TYPE lines of a gauge
+func (m metricWriter) header(name, help string) {
+	fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// sample writes a sample; labels are name/value pairs
+func (m metricWriter) sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(m.w, "%s %s\n", b.String(), strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// gauge writes a gauge with a single sample
+func (m metricWriter) gauge(name, help string, value float64, labels ...string) {
+	m.header(name, help)
+	m.sample(name, value, labels...)
+}
+
+// kindLabel returns the label value of a change kind
+func kindLabel(kind git.ChangeKind) string {
+	return strings.ReplaceAll(kind.String(), " ", "_")
+}
+
+// boolValue converts a flag to a sample value
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// metricsRepositories returns the repositories that get their own series:
+// with MetricsMaxRepos set, those with the most changes
+func metricsRepositories(r *report.Report, opts FormatOptions) []*git.Repository {
+	if opts.MetricsRepoLabel == RepoLabelNone {
+		return nil
+	}
+	repos := append([]*git.Repository(nil), r.Repositories...)
+	if opts.MetricsMaxRepos > 0 && len(repos) > opts.MetricsMaxRepos {
+		sort.SliceStable(repos, func(i, j int) bool {
+			return len(repos[i].Changes) > len(repos[j].Changes)
+		})
+		repos = repos[:opts.MetricsMaxRepos]
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].Path < repos[j].Path
+	})
+	return repos
+}
+
+// formatPrometheus formats the report as Prometheus metrics, e.g. for the
+// textfile collector of node_exporter
+func formatPrometheus(w io.Writer, r *report.Report, opts FormatOptions) error {
+	bw := bufio.NewWriter(w)
+	m := metricWriter{bw}
+	root := []string{"root", r.Root}
+
+	var dirty, unpushed, stashed int
+	for _, repo := range r.Repositories {
+		if repo.IsDirty() {
+			dirty++
+		}
+		if repo.HasUnpushed() {
+			unpushed++
+		}
+		if repo.HasStashes() {
+			stashed++
+		}
+	}
+
+	m.gauge("gus_repositories_total", "Number of Git repositories found by the scan.", float64(r.Total()), root...)
+	m.gauge("gus_repositories_reported", "Number of Git repositories reported by the scan.", float64(len(r.Repositories)), root...)
+	m.gauge("gus_repositories_dirty", "Number of reported Git repositories with uncommitted changes.", float64(dirty), root...)
+	m.gauge("gus_repositories_unpushed", "Number of reported Git repositories with unpushed commits.", float64(unpushed), root...)
+	m.gauge("gus_repositories_stashed", "Number of reported Git repositories with stashed changes.", float64(stashed), root...)
+	m.gauge("gus_repositories_failed", "Number of Git repositories that could not be checked.", float64(len(r.Errors)), root...)
+	m.gauge("gus_scan_duration_seconds", "Duration of the scan in seconds.", r.Duration.Seconds(), root...)
+	m.gauge("gus_scan_timestamp_seconds", "Time the scan started, in seconds since the epoch.", float64(reportTime(r).UnixMilli())/1000, root...)
+	m.gauge("gus_scan_interrupted", "Whether the scan was interrupted before it finished.", boolValue(r.Interrupted), root...)
+
+	repos := metricsRepositories(r, opts)
+	if opts.MetricsRepoLabel != RepoLabelNone {
+		m.gauge("gus_repositories_omitted", "Number of reported Git repositories left out of the per-repository metrics.",
+			float64(len(r.Repositories)-len(repos)), root...)
+	}
+	if len(repos) == 0 {
+		return bw.Flush()
+	}
+
+	label := func(repo *git.Repository) string {
+		if opts.MetricsRepoLabel == RepoLabelRelPath {
+			return relPath(r.Root, repo.Path)
+		}
+		return repo.Path
+	}
+
+	m.header("gus_repository_changes", "Number of uncommitted changes of a repository by kind.")
+	for _, repo := range repos {
+		counts := make(map[git.ChangeKind]int)
+		var kinds []git.ChangeKind
+		for _, change := range repo.Changes {
+			if counts[change.Kind] == 0 {
+				kinds = append(kinds, change.Kind)
+			}
+			counts[change.Kind]++
+		}
+		sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+		for _, kind := range kinds {
+			m.sample("gus_repository_changes", float64(counts[kind]), "repo", label(repo), "kind", kindLabel(kind))
+		}
+	}
+
+	m.header("gus_repository_ahead", "Number of commits of the current branch not on its upstream.")
+	for _, repo := range repos {
+		m.sample("gus_repository_ahead", float64(repo.Ahead), "repo", label(repo))
+	}
+	m.header("gus_repository_behind", "Number of commits of the upstream not on the current branch.")
+	for _, repo := range repos {
+		m.sample("gus_repository_behind", float64(repo.Behind), "repo", label(repo))
+	}
+	m.header("gus_repository_stashes", "Number of stash entries of a repository.")
+	for _, repo := range repos {
+		m.sample("gus_repository_stashes", float64(len(repo.Stashes)), "repo", label(repo))
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,96 @@
+package formatter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func TestFormatPrometheus(t *testing.T) {
+	r := &report.Report{
+		Root: "/src",
+		Repositories: []*git.Repository{
+			{
+				Path:  "/src/b",
+				Ahead: 2,
+				Changes: []git.Change{
+					git.NewChange(git.StatusModified, git.StatusUnmodified, "main.go", ""),
+					git.NewChange(git.StatusUntracked, git.StatusUntracked, "x.txt", ""),
+					git.NewChange(git.StatusUntracked, git.StatusUntracked, "y.txt", ""),
+				},
+			},
+			{
+				Path:    `/src/a "quoted"`,
+				Behind:  1,
+				Stashes: []git.Stash{{Index: 0, Message: "WIP"}},
+				Changes: []git.Change{
+					git.NewChange(git.StatusTypeChanged, git.StatusUnmodified, "link", ""),
+				},
+			},
+		},
+		Clean:     []*git.Repository{{Path: "/src/clean"}},
+		StartTime: scanTime,
+		Duration:  1500 * time.Millisecond,
+	}
+
+	// Test case 1: All metrics
+	var buf bytes.Buffer
+	if err := Format(&buf, r, FormatOptions{Format: "prometheus"}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	output := buf.String()
+	for _, s := range []string{
+		"# HELP gus_repositories_total Number of Git repositories found by the scan.\n# TYPE gus_repositories_total gauge\n",
+		`gus_repositories_total{root="/src"} 3`,
+		`gus_repositories_dirty{root="/src"} 2`,
+		`gus_repositories_unpushed{root="/src"} 1`,
+		`gus_repositories_stashed{root="/src"} 1`,
+		`gus_scan_duration_seconds{root="/src"} 1.5`,
+		`gus_scan_timestamp_seconds{root="/src"} 1710930600`,
+		`gus_repositories_omitted{root="/src"} 0`,
+		`gus_repository_changes{repo="/src/a \"quoted\"",kind="type_changed"} 1`,
+		`gus_repository_changes{repo="/src/b",kind="modified"} 1`,
+		`gus_repository_changes{repo="/src/b",kind="untracked"} 2`,
+		`gus_repository_ahead{repo="/src/b"} 2`,
+		`gus_repository_behind{repo="/src/a \"quoted\""} 1`,
+		`gus_repository_stashes{repo="/src/a \"quoted\""} 1`,
+	} {
+		if !strings.Contains(output, s) {
+			t.Errorf("Expected output to contain %q, got:\n%s", s, output)
+		}
+	}
+
+	// Test case 2: Relative labels and a limit on the repositories
+	buf.Reset()
+	if err := Format(&buf, r, FormatOptions{Format: "prometheus", MetricsRepoLabel: RepoLabelRelPath, MetricsMaxRepos: 1}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	output = buf.String()
+	if !strings.Contains(output, `gus_repository_ahead{repo="b"} 2`) {
+		t.Errorf("Expected the repository with the most changes to be kept, got:\n%s", output)
+	}
+	if strings.Contains(output, "quoted") {
+		t.Errorf("Expected the other repository to be left out, got:\n%s", output)
+	}
+	if !strings.Contains(output, `gus_repositories_omitted{root="/src"} 1`) {
+		t.Errorf("Expected the omitted repositories to be counted, got:\n%s", output)
+	}
+
+	// Test case 3: No per-repository metrics
+	buf.Reset()
+	if err := Format(&buf, r, FormatOptions{Format: "prometheus", MetricsRepoLabel: RepoLabelNone}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "gus_repository_") || !strings.Contains(buf.String(), "gus_repositories_total") {
+		t.Errorf("Expected only aggregate metrics, got:\n%s", buf.String())
+	}
+
+	// Test case 4: Invalid label settings
+	if _, err := ParseRepoLabel("name"); err == nil {
+		t.Error("Expected an error for an invalid repository label")
+	}
+}
@@ -0,0 +1,62 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Formatter)
+)
+
+// Register makes a formatter available under a name, so that it can be
+// selected with FormatOptions.Format and the --format flag. Register is
+// meant to be called from init functions; it panics if the name is empty,
+// the formatter is nil or the name is already taken.
+func Register(name string, f Formatter) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("formatter: Register with an empty name")
+	}
+	if f == nil {
+		panic("formatter: Register formatter is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("formatter: Register called twice for " + name)
+	}
+	registry[name] = f
+}
+
+// Lookup returns the formatter registered under a name
+func Lookup(name string) (Formatter, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	f, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(names(), ", "))
+	}
+	return f, nil
+}
+
+// Names returns the names of the registered formats in alphabetical order
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return names()
+}
+
+// names lists the registered formats; the caller must hold registryMu
+func names() []string {
+	list := make([]string, 0, len(registry))
+	for name := range registry {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
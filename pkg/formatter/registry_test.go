@@ -0,0 +1,60 @@
+package formatter
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func TestRegistry(t *testing.T) {
+	// Test case 1: Built-in formats are registered
+	for _, name := range []string{"text", "json"} {
+		if _, err := Lookup(name); err != nil {
+			t.Errorf("Expected format %q to be registered: %v", name, err)
+		}
+	}
+
+	// Test case 2: Custom formats can be registered and selected
+	Register("test-count", FormatterFunc(func(w io.Writer, r *report.Report, opts FormatOptions) error {
+		_, err := io.WriteString(w, strings.Repeat("x", len(r.Repositories)))
+		return err
+	}))
+	var buf bytes.Buffer
+	r := &report.Report{Repositories: []*git.Repository{{Path: "/a"}, {Path: "/b"}}}
+	if err := Format(&buf, r, FormatOptions{Format: "test-count"}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if buf.String() != "xx" {
+		t.Errorf("Expected custom output %q, got %q", "xx", buf.String())
+	}
+
+	names := Names()
+	found := false
+	for i, name := range names {
+		if i > 0 && names[i-1] > name {
+			t.Errorf("Expected names to be sorted, got %v", names)
+		}
+		found = found || name == "test-count"
+	}
+	if !found {
+		t.Errorf("Expected names to include the custom format, got %v", names)
+	}
+
+	// Test case 3: Unknown formats list the available ones
+	err := Format(&buf, r, FormatOptions{Format: "nope"})
+	if err == nil || !strings.Contains(err.Error(), "available: ") || !strings.Contains(err.Error(), "test-count") {
+		t.Errorf("Expected an error listing the available formats, got %v", err)
+	}
+
+	// Test case 4: Registering a name twice panics
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic when registering a format twice")
+		}
+	}()
+	Register("json", FormatterFunc(formatJSON))
+}
@@ -0,0 +1,274 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("sarif", FormatterFunc(formatSARIF))
+}
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// sarifInformationURI is where the tool is documented
+	sarifInformationURI = "https://github.com/nguyendangminh/gus"
+	// largeFileSize is the size from which an untracked file is reported
+	// with the large-untracked-file rule
+	largeFileSize = 10 << 20
+)
+
+// sarifRule describes one kind of uncommitted change
+type sarifRule struct {
+	ID                   string         `json:"id"`
+	Name                 string         `json:"name"`
+	ShortDescription     sarifMessage   `json:"shortDescription"`
+	DefaultConfiguration sarifRuleLevel `json:"defaultConfiguration"`
+}
+
+// sarifRuleLevel is the default severity of a rule
+type sarifRuleLevel struct {
+	Level string `json:"level"`
+}
+
+// sarifRules are the rules of the sarif format, one per change kind
+var sarifRules = []sarifRule{
+	{ID: "modified", Name: "ModifiedFile", ShortDescription: sarifMessage{"File with uncommitted modifications"}, DefaultConfiguration: sarifRuleLevel{"warning"}},
+	{ID: "type-changed", Name: "TypeChangedFile", ShortDescription: sarifMessage{"File whose type changed and is not committed"}, DefaultConfiguration: sarifRuleLevel{"warning"}},
+	{ID: "added", Name: "AddedFile", ShortDescription: sarifMessage{"New file that is staged but not committed"}, DefaultConfiguration: sarifRuleLevel{"warning"}},
+	{ID: "deleted", Name: "DeletedFile", ShortDescription: sarifMessage{"File deletion that is not committed"}, DefaultConfiguration: sarifRuleLevel{"warning"}},
+	{ID: "renamed", Name: "RenamedFile", ShortDescription: sarifMessage{"File rename that is not committed"}, DefaultConfiguration: sarifRuleLevel{"warning"}},
+	{ID: "copied", Name: "CopiedFile", ShortDescription: sarifMessage{"File copy that is not committed"}, DefaultConfiguration: sarifRuleLevel{"warning"}},
+	{ID: "conflict", Name: "ConflictedFile", ShortDescription: sarifMessage{"File with unresolved merge conflicts"}, DefaultConfiguration: sarifRuleLevel{"error"}},
+	{ID: "untracked", Name: "UntrackedFile", ShortDescription: sarifMessage{"File that is not tracked by Git"}, DefaultConfiguration: sarifRuleLevel{"note"}},
+	{ID: "large-untracked-file", Name: "LargeUntrackedFile", ShortDescription: sarifMessage{"Large file that is not tracked by Git"}, DefaultConfiguration: sarifRuleLevel{"warning"}},
+	{ID: "unknown", Name: "UnknownChange", ShortDescription: sarifMessage{"Uncommitted change of an unknown kind"}, DefaultConfiguration: sarifRuleLevel{"warning"}},
+}
+
+// sarifLog is the root object of a SARIF file
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// sarifRun is the single run of gus over the scanned directory
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	Invocations        []sarifInvocation                `json:"invocations"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+// sarifTool describes gus and its rules
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+// sarifDriver is the component of the tool that produced the results
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+// sarifInvocation holds the scan metadata
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	StartTimeUTC               string              `json:"startTimeUtc"`
+	EndTimeUTC                 string              `json:"endTimeUtc"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+// sarifNotification reports a path that could not be read or checked
+type sarifNotification struct {
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+// sarifResult is an uncommitted file
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	RuleIndex  int               `json:"ruleIndex"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// sarifMessage is a plain text message
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifLocation is where a result was found
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+// sarifPhysicalLocation points at a file
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+// sarifArtifactLocation is a URI, relative to the base named by URIBaseID
+// if set
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// sarifRuleID returns the rule of a change
+func sarifRuleID(change git.Change) string {
+	switch change.Kind {
+	case git.ChangeModified:
+		return "modified"
+	case git.ChangeTypeChanged:
+		return "type-changed"
+	case git.ChangeAdded:
+		return "added"
+	case git.ChangeDeleted:
+		return "deleted"
+	case git.ChangeRenamed:
+		return "renamed"
+	case git.ChangeCopied:
+		return "copied"
+	case git.ChangeUnmerged:
+		return "conflict"
+	case git.ChangeUntracked:
+		if change.Size >= largeFileSize {
+			return "large-untracked-file"
+		}
+		return "untracked"
+	}
+	return "unknown"
+}
+
+// sarifRuleIndex returns the index of a rule in sarifRules
+func sarifRuleIndex(id string) int {
+	for i, rule := range sarifRules {
+		if rule.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// fileURI returns the file URI of a directory, with a trailing slash as
+// SARIF requires for base URIs
+func fileURI(dir string) string {
+	path := filepath.ToSlash(dir)
+	if !strings.HasPrefix(path, "/") {
+		// Windows drive letters
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: strings.TrimSuffix(path, "/") + "/"}).String()
+}
+
+// relativeURI returns the URI reference of a relative file path
+func relativeURI(path string) string {
+	return (&url.URL{Path: filepath.ToSlash(path)}).String()
+}
+
+// formatSARIF formats the report as a SARIF log with a result per
+// uncommitted file. Locations are relative to the working tree of their
+// repository, which is named by the base ID of the result.
+func formatSARIF(w io.Writer, r *report.Report, opts FormatOptions) error {
+	start := reportTime(r)
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "gus",
+			Version:        Version,
+			InformationURI: sarifInformationURI,
+			Rules:          sarifRules,
+		}},
+		Invocations: []sarifInvocation{{
+			ExecutionSuccessful: !r.Interrupted && len(r.Errors) == 0,
+			StartTimeUTC:        start.UTC().Format("2006-01-02T15:04:05.000Z"),
+			EndTimeUTC:          start.Add(r.Duration).UTC().Format("2006-01-02T15:04:05.000Z"),
+		}},
+		OriginalURIBaseIDs: map[string]sarifArtifactLocation{},
+		Results:            []sarifResult{},
+	}
+
+	invocation := &run.Invocations[0]
+	for _, warning := range r.Warnings {
+		invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, sarifNotification{
+			Level:     "warning",
+			Message:   sarifMessage{"Skipped unreadable path: " + warning.Err.Error()},
+			Locations: []sarifLocation{{sarifPhysicalLocation{sarifArtifactLocation{URI: fileURI(warning.Path)}}}},
+		})
+	}
+	for _, e := range r.Errors {
+		invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, sarifNotification{
+			Level:     "error",
+			Message:   sarifMessage{"Failed to check repository: " + e.Err.Error()},
+			Locations: []sarifLocation{{sarifPhysicalLocation{sarifArtifactLocation{URI: fileURI(e.Path)}}}},
+		})
+	}
+
+	for _, repo := range r.Repositories {
+		baseID := relPath(r.Root, repo.Path)
+		run.OriginalURIBaseIDs[baseID] = sarifArtifactLocation{URI: fileURI(repo.Path)}
+
+		for _, change := range repo.Changes {
+			id := sarifRuleID(change)
+			index := sarifRuleIndex(id)
+			result := sarifResult{
+				RuleID:    id,
+				RuleIndex: index,
+				Level:     sarifRules[index].DefaultConfiguration.Level,
+				Message:   sarifMessage{sarifResultMessage(id, change)},
+				Locations: []sarifLocation{{sarifPhysicalLocation{sarifArtifactLocation{
+					URI:       relativeURI(change.Path),
+					URIBaseID: baseID,
+				}}}},
+				Properties: map[string]string{
+					"repository": repo.Path,
+					"index":      change.Index.String(),
+					"worktree":   change.Worktree.String(),
+				},
+			}
+			if repo.Branch != "" {
+				result.Properties["branch"] = repo.Branch
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifResultMessage describes a result
+func sarifResultMessage(ruleID string, change git.Change) string {
+	switch ruleID {
+	case "conflict":
+		return fmt.Sprintf("%s has unresolved merge conflicts.", change.Path)
+	case "untracked":
+		return fmt.Sprintf("%s is not tracked by Git.", change.Path)
+	case "large-untracked-file":
+		if strings.HasSuffix(change.Path, "/") {
+			return fmt.Sprintf("%s contains a large file that is not tracked by Git.", change.Path)
+		}
+		return fmt.Sprintf("%s is a large file that is not tracked by Git.", change.Path)
+	case "renamed", "copied":
+		return fmt.Sprintf("%s was %s from %s and is not committed.", change.Path, change.Kind, change.OrigPath)
+	}
+	return fmt.Sprintf("%s is %s and not committed.", change.Path, change.Kind)
+}
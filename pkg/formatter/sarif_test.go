@@ -0,0 +1,106 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func TestFormatSARIF(t *testing.T) {
+	root := t.TempDir()
+	repoDir := filepath.Join(root, "my app")
+	big := git.NewChange(git.StatusUntracked, git.StatusUntracked, "dump.bin", "")
+	big.Size = largeFileSize
+	bigDir := git.NewChange(git.StatusUntracked, git.StatusUntracked, "new dir/", "")
+	bigDir.Size = largeFileSize + 1
+
+	r := &report.Report{
+		Root: root,
+		Repositories: []*git.Repository{{
+			Path:   repoDir,
+			Branch: "main",
+			Changes: []git.Change{
+				git.NewChange(git.StatusModified, git.StatusUnmodified, "src/main.go", ""),
+				git.NewChange(git.StatusUnmerged, git.StatusUnmerged, "conflict.go", ""),
+				git.NewChange(git.StatusRenamed, git.StatusUnmodified, "new name.go", "old.go"),
+				git.NewChange(git.StatusUntracked, git.StatusUntracked, "notes.txt", ""),
+				big,
+				bigDir,
+			},
+		}},
+		StartTime: scanTime,
+		Duration:  2 * time.Second,
+	}
+
+	var buf bytes.Buffer
+	if err := Format(&buf, r, FormatOptions{Format: "sarif"}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("Failed to parse output: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Expected a SARIF 2.1.0 log with one run, got %+v", log)
+	}
+	run := log.Runs[0]
+
+	// Test case 1: Scan metadata
+	if run.Tool.Driver.Name != "gus" || len(run.Tool.Driver.Rules) != len(sarifRules) {
+		t.Errorf("Unexpected tool: %+v", run.Tool)
+	}
+	if inv := run.Invocations[0]; !inv.ExecutionSuccessful || inv.StartTimeUTC != "2024-03-20T10:30:00.000Z" || inv.EndTimeUTC != "2024-03-20T10:30:02.000Z" {
+		t.Errorf("Unexpected invocation: %+v", inv)
+	}
+
+	// Test case 2: The repository is a base URI
+	base, ok := run.OriginalURIBaseIDs["my app"]
+	if !ok || base.URI != fileURI(repoDir) {
+		t.Errorf("Expected a base URI for the repository, got %v", run.OriginalURIBaseIDs)
+	}
+
+	// Test case 3: One result per file, with a rule per kind
+	expected := []struct {
+		ruleID, level, uri string
+	}{
+		{"modified", "warning", "src/main.go"},
+		{"conflict", "error", "conflict.go"},
+		{"renamed", "warning", "new%20name.go"},
+		{"untracked", "note", "notes.txt"},
+		{"large-untracked-file", "warning", "dump.bin"},
+		{"large-untracked-file", "warning", "new%20dir/"},
+	}
+	if len(run.Results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(run.Results))
+	}
+	for i, want := range expected {
+		result := run.Results[i]
+		location := result.Locations[0].PhysicalLocation.ArtifactLocation
+		if result.RuleID != want.ruleID || result.Level != want.level || location.URI != want.uri || location.URIBaseID != "my app" {
+			t.Errorf("Result %d: expected %+v, got %+v at %+v", i, want, result, location)
+		}
+		if sarifRules[result.RuleIndex].ID != result.RuleID {
+			t.Errorf("Result %d: rule index %d does not match %s", i, result.RuleIndex, result.RuleID)
+		}
+	}
+	if msg := run.Results[2].Message.Text; msg != "new name.go was renamed from old.go and is not committed." {
+		t.Errorf("Unexpected message %q", msg)
+	}
+	if msg := run.Results[5].Message.Text; msg != "new dir/ contains a large file that is not tracked by Git." {
+		t.Errorf("Unexpected message %q", msg)
+	}
+}
+
+func TestFileURI(t *testing.T) {
+	if got := fileURI("/src/my app"); got != "file:///src/my%20app/" {
+		t.Errorf("Unexpected URI %q", got)
+	}
+	if got := fileURI("/"); got != "file:///" {
+		t.Errorf("Unexpected URI %q", got)
+	}
+}
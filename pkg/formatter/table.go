@@ -0,0 +1,204 @@
+package formatter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("table", FormatterFunc(formatTable))
+}
+
+const (
+	// columnGap separates table columns
+	columnGap = "  "
+	// minPathWidth is the narrowest the path column is truncated to
+	minPathWidth = 16
+	// maxBranchWidth is the widest the branch column grows
+	maxBranchWidth = 24
+)
+
+// tableHeader are the column titles of the table format
+var tableHeader = []string{"PATH", "BRANCH", "STAGED", "UNSTAGED", "UNTRACKED", "AHEAD", "BEHIND", "LAST COMMIT"}
+
+// numericColumns are right-aligned
+var numericColumns = map[int]bool{2: true, 3: true, 4: true, 5: true, 6: true}
+
+// columnStyles color the non-zero counts of the change columns
+var columnStyles = map[int]string{2: styleStaged, 3: styleUnstaged, 4: styleUntracked}
+
+// changeCounts counts the changes of a repository by where they are
+type changeCounts struct {
+	staged, unstaged, untracked int
+}
+
+// countChanges sorts the changes of a repository into staged, unstaged
+// and untracked. Conflicts still need work in the working tree and count
+// as unstaged.
+func countChanges(changes []git.Change) changeCounts {
+	var c changeCounts
+	for _, change := range changes {
+		switch change.Kind {
+		case git.ChangeUntracked:
+			c.untracked++
+			continue
+		case git.ChangeUnmerged:
+			c.unstaged++
+			continue
+		}
+		if change.Staged() {
+			c.staged++
+		}
+		if change.Unstaged() {
+			c.unstaged++
+		}
+	}
+	return c
+}
+
+// formatTable formats the report as a table with one row per repository
+// and a totals row, fitted to the width of the terminal
+func formatTable(w io.Writer, r *report.Report, opts FormatOptions) error {
+	bw := bufio.NewWriter(w)
+
+	if opts.Verbose {
+		fmt.Fprintf(bw, "Found %d Git repositories in %s\n", r.Total(), r.Duration.Round(time.Millisecond))
+	}
+
+	if len(r.Repositories) == 0 {
+		fmt.Fprintln(bw, "No Git repositories with uncommitted changes found.")
+		formatProblems(bw, r)
+		return bw.Flush()
+	}
+
+	rows := [][]string{tableHeader}
+	var total changeCounts
+	var ahead, behind int
+	for _, repo := range r.Repositories {
+		c := countChanges(repo.Changes)
+		total.staged += c.staged
+		total.unstaged += c.unstaged
+		total.untracked += c.untracked
+		ahead += repo.Ahead
+		behind += repo.Behind
+
+		branch := repo.Branch
+		if branch == "" {
+			branch = "(" + string(git.StateDetached) + ")"
+		}
+		lastCommit := "-"
+		if !repo.LastCommit.IsZero() {
+			lastCommit = formatAge(reportTime(r).Sub(repo.LastCommit))
+		}
+		rows = append(rows, []string{
+			relPath(r.Root, repo.Path),
+			truncateRight(branch, maxBranchWidth),
+			strconv.Itoa(c.staged),
+			strconv.Itoa(c.unstaged),
+			strconv.Itoa(c.untracked),
+			strconv.Itoa(repo.Ahead),
+			strconv.Itoa(repo.Behind),
+			lastCommit,
+		})
+	}
+	rows = append(rows, []string{
+		fmt.Sprintf("TOTAL (%s)", plural(len(r.Repositories), "repo")),
+		"",
+		strconv.Itoa(total.staged),
+		strconv.Itoa(total.unstaged),
+		strconv.Itoa(total.untracked),
+		strconv.Itoa(ahead),
+		strconv.Itoa(behind),
+		"",
+	})
+
+	color := colorizer(opts.useColor(w))
+	widths := columnWidths(rows, opts.Width, w)
+	for n, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if i == 0 {
+				cell = truncateLeft(cell, widths[i])
+			}
+			cells[i] = pad(cell, widths[i], numericColumns[i])
+			if n > 0 && cell != "0" && cell != "" {
+				// Pad first so that escape sequences do not count as width
+				cells[i] = color.wrap(columnStyles[i], cells[i])
+			}
+		}
+		fmt.Fprintln(bw, strings.TrimRight(strings.Join(cells, columnGap), " "))
+	}
+
+	formatProblems(bw, r)
+	return bw.Flush()
+}
+
+// columnWidths returns the width of each column. The path column is
+// shrunk, down to minPathWidth, so that rows fit in the available width:
+// width if positive, unlimited if negative, and the width of the terminal
+// w writes to if zero.
+func columnWidths(rows [][]string, width int, w io.Writer) []int {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	if width == 0 {
+		width = outputWidth(w)
+	}
+	if width <= 0 {
+		return widths
+	}
+
+	used := len(columnGap) * (len(widths) - 1)
+	for _, n := range widths[1:] {
+		used += n
+	}
+	if available := width - used; widths[0] > available {
+		widths[0] = max(available, minPathWidth)
+	}
+	return widths
+}
+
+// pad pads s with spaces to width, on the left if alignRight is set
+func pad(s string, width int, alignRight bool) string {
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	if alignRight {
+		return strings.Repeat(" ", n) + s
+	}
+	return s + strings.Repeat(" ", n)
+}
+
+// truncateLeft shortens s to width by replacing its beginning with an
+// ellipsis, keeping the end of paths which tells them apart
+func truncateLeft(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return "…" + string(runes[len(runes)-width+1:])
+}
+
+// truncateRight shortens s to width by replacing its end with an ellipsis
+func truncateRight(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,90 @@
+package formatter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func TestFormatTable(t *testing.T) {
+	r := &report.Report{
+		Root: "/src",
+		Repositories: []*git.Repository{
+			{
+				Path:       "/src/app",
+				Branch:     "main",
+				Ahead:      2,
+				LastCommit: scanTime.Add(-3 * 24 * time.Hour),
+				Changes: []git.Change{
+					git.NewChange(git.StatusModified, git.StatusModified, "main.go", ""),
+					git.NewChange(git.StatusAdded, git.StatusUnmodified, "new.go", ""),
+					git.NewChange(git.StatusUntracked, git.StatusUntracked, "notes.txt", ""),
+				},
+			},
+			{
+				Path:   "/src/very/deeply/nested/library",
+				Behind: 1,
+				Changes: []git.Change{
+					git.NewChange(git.StatusUnmerged, git.StatusUnmerged, "conflict.go", ""),
+				},
+			},
+		},
+		StartTime: scanTime,
+	}
+
+	// Test case 1: Unlimited width
+	var buf bytes.Buffer
+	if err := Format(&buf, r, FormatOptions{Format: "table", Width: -1}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	expected := strings.Join([]string{
+		"PATH                        BRANCH      STAGED  UNSTAGED  UNTRACKED  AHEAD  BEHIND  LAST COMMIT",
+		"app                         main             2         1          1      2       0  3 days ago",
+		"very/deeply/nested/library  (detached)       0         1          0      0       1  -",
+		"TOTAL (2 repos)                              2         2          1      2       1",
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("Unexpected table:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	// Test case 2: Long paths are truncated to fit
+	buf.Reset()
+	if err := Format(&buf, r, FormatOptions{Format: "table", Width: 85}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for _, line := range lines {
+		if n := len([]rune(line)); n > 85 {
+			t.Errorf("Expected lines of at most 85 columns, got %d: %q", n, line)
+		}
+	}
+	if !strings.HasPrefix(lines[2], "…/nested/library  (detached)") {
+		t.Errorf("Expected the long path to be truncated from the left, got %q", lines[2])
+	}
+
+	// Test case 3: Empty report
+	buf.Reset()
+	if err := Format(&buf, &report.Report{}, FormatOptions{Format: "table"}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No Git repositories with uncommitted changes found.") {
+		t.Errorf("Unexpected output for an empty report: %q", buf.String())
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncateLeft("abcdefgh", 5); got != "…efgh" {
+		t.Errorf("truncateLeft: expected %q, got %q", "…efgh", got)
+	}
+	if got := truncateRight("abcdefgh", 5); got != "abcd…" {
+		t.Errorf("truncateRight: expected %q, got %q", "abcd…", got)
+	}
+	if got := truncateLeft("abc", 5); got != "abc" {
+		t.Errorf("truncateLeft: expected %q, got %q", "abc", got)
+	}
+}
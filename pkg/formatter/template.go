@@ -0,0 +1,137 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("template", FormatterFunc(formatTemplate))
+}
+
+// ParseTemplate parses the text of an output template, reporting syntax
+// errors and unknown functions before a scan is started
+func ParseTemplate(text string) (*template.Template, error) {
+	return parseTemplate(text, &report.Report{}, false)
+}
+
+// parseTemplate parses an output template with helper functions bound to r
+func parseTemplate(text string, r *report.Report, color colorizer) (*template.Template, error) {
+	t, err := template.New("output").Funcs(templateFuncs(r, color)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return t, nil
+}
+
+// templateFuncs returns the helper functions available to templates
+func templateFuncs(r *report.Report, color colorizer) template.FuncMap {
+	return template.FuncMap{
+		// relpath returns a path relative to the scanned root
+		"relpath": func(path string) string {
+			return relPath(r.Root, path)
+		},
+		// count returns the number of elements of a list, or 0 for nil
+		"count": func(v interface{}) (int, error) {
+			if v == nil {
+				return 0, nil
+			}
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+				return rv.Len(), nil
+			}
+			return 0, fmt.Errorf("count: cannot count %T", v)
+		},
+		// join joins the elements of a list with a separator; it takes the
+		// list last so that it can be used in pipelines
+		"join": func(sep string, v interface{}) (string, error) {
+			if v == nil {
+				return "", nil
+			}
+			rv := reflect.ValueOf(v)
+			if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+				return "", fmt.Errorf("join: cannot join %T", v)
+			}
+			parts := make([]string, rv.Len())
+			for i := range parts {
+				parts[i] = fmt.Sprint(rv.Index(i).Interface())
+			}
+			return strings.Join(parts, sep), nil
+		},
+		// color wraps text in the ANSI escape sequence of a color, if
+		// output is colored
+		"color": func(name string, v interface{}) (string, error) {
+			code, ok := colors[name]
+			if !ok {
+				return "", fmt.Errorf("color: unknown color %q", name)
+			}
+			return color.wrap(code, fmt.Sprint(v)), nil
+		},
+	}
+}
+
+// templateData returns the JSON document of the report as generic values,
+// so that templates use the same field names as the JSON output. Whole
+// numbers are decoded as int so that they compare with integer constants.
+func templateData(r *report.Report) (interface{}, error) {
+	doc, err := json.Marshal(newJSONOutput(r))
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	return convertNumbers(data), nil
+}
+
+// convertNumbers replaces the json.Number values of a decoded document
+// with int or float64
+func convertNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = convertNumbers(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = convertNumbers(value)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// formatTemplate renders the report through the template of the options
+func formatTemplate(w io.Writer, r *report.Report, opts FormatOptions) error {
+	if opts.Template == "" {
+		return errors.New("the template format requires a template")
+	}
+
+	t, err := parseTemplate(opts.Template, r, colorizer(opts.useColor(w)))
+	if err != nil {
+		return err
+	}
+	data, err := templateData(r)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, data)
+}
@@ -0,0 +1,116 @@
+package formatter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func TestFormatTemplate(t *testing.T) {
+	r := &report.Report{
+		Root: "/src",
+		Repositories: []*git.Repository{
+			{
+				Path:   "/src/app",
+				Branch: "main",
+				Ahead:  2,
+				Changes: []git.Change{
+					git.NewChange(git.StatusModified, git.StatusUnmodified, "main.go", ""),
+					git.NewChange(git.StatusUntracked, git.StatusUntracked, "notes.txt", ""),
+				},
+			},
+			{Path: "/src/lib/util", Branch: "dev"},
+		},
+		Clean:     []*git.Repository{{Path: "/src/clean"}},
+		StartTime: scanTime,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "one path per line",
+			template: `{{range .repositories}}{{println .path}}{{end}}`,
+			expected: "/src/app\n/src/lib/util\n",
+		},
+		{
+			name:     "relative paths and counts",
+			template: `{{range .repositories}}{{relpath .path}}: {{count .changes}} changes{{"\n"}}{{end}}`,
+			expected: "app: 2 changes\nlib/util: 0 changes\n",
+		},
+		{
+			name:     "nested fields",
+			template: `{{range .repositories}}{{range .changes}}{{.path}} {{end}}{{end}}| {{.metadata.scanned_repositories}}`,
+			expected: "main.go notes.txt | 3",
+		},
+		{
+			name:     "numbers compare with integers",
+			template: `{{range .repositories}}{{if gt .ahead 0}}{{.branch}} ahead {{.ahead}}{{end}}{{end}}`,
+			expected: "main ahead 2",
+		},
+		{
+			name:     "color",
+			template: `{{color "red" "dirty"}}`,
+			expected: "\x1b[31mdirty\x1b[0m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Format(&buf, r, FormatOptions{Template: tt.template, Color: ColorAlways}); err != nil {
+				t.Fatalf("Format failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+
+	// The data has the fields of the JSON output
+	data, err := templateData(r)
+	if err != nil {
+		t.Fatalf("templateData failed: %v", err)
+	}
+	if _, ok := data.(map[string]interface{})["metadata"]; !ok {
+		t.Errorf("Expected the template data to have the JSON fields, got %v", data)
+	}
+
+	// join takes the list last so that it works in pipelines
+	var buf bytes.Buffer
+	tmpl, err := parseTemplate(`{{.names | join ", "}}`, r, false)
+	if err != nil {
+		t.Fatalf("parseTemplate failed: %v", err)
+	}
+	if err := tmpl.Execute(&buf, map[string]interface{}{"names": []string{"a", "b"}}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if buf.String() != "a, b" {
+		t.Errorf("Expected %q, got %q", "a, b", buf.String())
+	}
+}
+
+func TestFormatTemplateErrors(t *testing.T) {
+	for _, text := range []string{
+		`{{range .repositories}`,
+		`{{nosuchfunc .}}`,
+	} {
+		if _, err := ParseTemplate(text); err == nil {
+			t.Errorf("Expected ParseTemplate(%q) to fail", text)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := Format(&buf, &report.Report{}, FormatOptions{Template: `{{color "plaid" "x"}}`})
+	if err == nil || !strings.Contains(err.Error(), "unknown color") {
+		t.Errorf("Expected an unknown color error, got %v", err)
+	}
+	if err := Format(&buf, &report.Report{}, FormatOptions{Format: "template"}); err == nil {
+		t.Error("Expected an error for the template format without a template")
+	}
+}
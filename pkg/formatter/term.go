@@ -0,0 +1,32 @@
+package formatter
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// defaultWidth is assumed for terminals whose size cannot be determined
+const defaultWidth = 80
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// outputWidth returns the number of columns of w if it is a terminal, or 0
+// when output is not limited
+func outputWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(f) {
+		return 0
+	}
+	if n, ok := terminalSize(f); ok {
+		return n
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultWidth
+}
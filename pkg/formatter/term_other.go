@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package formatter
+
+import "os"
+
+// terminalSize is not supported on this platform; COLUMNS is used instead
+func terminalSize(f *os.File) (int, bool) {
+	return 0, false
+}
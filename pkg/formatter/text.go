@@ -0,0 +1,105 @@
+package formatter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/report"
+)
+
+func init() {
+	Register("text", FormatterFunc(formatText))
+}
+
+// formatText formats the report as text
+func formatText(w io.Writer, r *report.Report, opts FormatOptions) error {
+	bw := bufio.NewWriter(w)
+	color := colorizer(opts.useColor(w))
+
+	if opts.Verbose {
+		fmt.Fprintf(bw, "Found %d Git repositories in %s\n", r.Total(), r.Duration.Round(time.Millisecond))
+	}
+
+	if len(r.Repositories) == 0 {
+		fmt.Fprintln(bw, "No Git repositories with uncommitted changes found.")
+	} else {
+		fmt.Fprintf(bw, "Found %d Git repositories %s:\n\n", len(r.Repositories), reportedReason(r))
+	}
+
+	for i, repo := range r.Repositories {
+		// Format repository path
+		path := shortenPath(repo.Path)
+		switch repo.Kind {
+		case git.KindWorktree:
+			path += " (worktree of " + shortenPath(repo.Parent) + ")"
+		case git.KindSubmodule:
+			path += " (submodule of " + shortenPath(repo.Parent) + ")"
+		}
+		if repo.InProgress() {
+			path += " [" + string(repo.State) + "]"
+		}
+
+		fmt.Fprintf(bw, "%d. %s\n", i+1, path)
+
+		// Format branch tracking information
+		if repo.Ahead > 0 || repo.Behind > 0 {
+			fmt.Fprintf(bw, "   branch: %s -> %s (ahead %d, behind %d)\n", repo.Branch, repo.Upstream, repo.Ahead, repo.Behind)
+		}
+
+		// Format changes
+		for _, change := range repo.Changes {
+			fmt.Fprintf(bw, "   - %s\n", color.wrap(changeStyle(change), change.String()))
+		}
+
+		// Format unpushed branches
+		for _, branch := range repo.UnpushedBranches {
+			fmt.Fprintf(bw, "   - unpushed: %s (%s)\n", branch.Name, plural(branch.Commits, "commit"))
+		}
+
+		// Format stashes
+		for _, stash := range repo.Stashes {
+			fmt.Fprintf(bw, "   - %s: %s (%s)\n", stash.Name(), stash.Message, formatAge(stash.Age(reportTime(r))))
+		}
+		fmt.Fprintln(bw)
+	}
+
+	formatProblems(bw, r)
+	return bw.Flush()
+}
+
+// reportedReason describes why the repositories of a report are listed.
+// Repositories reported for unpushed commits, stashes or their state may
+// have no uncommitted changes at all.
+func reportedReason(r *report.Report) string {
+	for _, repo := range r.Repositories {
+		if !repo.IsDirty() {
+			return "that need attention"
+		}
+	}
+	return "with uncommitted changes"
+}
+
+// formatProblems writes the skipped paths, the repositories that could not
+// be checked and whether the scan was interrupted as a footer
+func formatProblems(w io.Writer, r *report.Report) {
+	if len(r.Warnings) > 0 {
+		fmt.Fprintf(w, "Skipped %d unreadable paths:\n", len(r.Warnings))
+		for _, warning := range r.Warnings {
+			fmt.Fprintf(w, "   - %s: %v\n", shortenPath(warning.Path), warning.Err)
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintf(w, "Failed to check %d Git repositories:\n", len(r.Errors))
+		for _, e := range r.Errors {
+			fmt.Fprintf(w, "   - %s: %v\n", shortenPath(e.Path), e.Err)
+		}
+	}
+
+	if r.Interrupted {
+		fmt.Fprintln(w, "Scan interrupted, results are incomplete.")
+	}
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnpushedBranch is a local branch with commits that are not on any remote
+type UnpushedBranch struct {
+	Name    string `json:"name"`
+	Commits int    `json:"commits"`
+}
+
+// IsDirty reports whether the working tree has uncommitted changes
+func (r *Repository) IsDirty() bool {
+	return len(r.Changes) > 0
+}
+
+// HasUnpushed reports whether the repository has commits that are not on
+// any remote, either on the current branch or on another local branch
+func (r *Repository) HasUnpushed() bool {
+	return r.Ahead > 0 || len(r.UnpushedBranches) > 0
+}
+
+// applyBranchStatus copies the branch headers of git status to the repository
+func (r *Repository) applyBranchStatus(branch branchStatus) {
+	if branch.head != "(detached)" {
+		r.Branch = branch.head
+	}
+	r.Upstream = branch.upstream
+	r.Ahead = branch.ahead
+	r.Behind = branch.behind
+}
+
+// unpushedBranches lists the local branches of a working tree with commits
+// that are not reachable from any remote-tracking branch. Branches are
+// shared by all worktrees of a repository, so a linked worktree only lists
+// the branch it has checked out, and the main working tree every branch not
+// checked out in a linked worktree.
+func unpushedBranches(ctx context.Context, repoPath, head string, linked bool) ([]UnpushedBranch, error) {
+	if linked && (head == "" || head == "(detached)") {
+		return nil, nil
+	}
+
+	// A single call lists every unpushed commit with its parents, which is
+	// enough to count the unpushed commits of all branches
+	out, err := gitOutput(ctx, repoPath, "rev-list", "--parents", "--branches", "--not", "--remotes")
+	if err != nil || strings.TrimSpace(out) == "" {
+		return nil, err
+	}
+	unpushed := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			unpushed[fields[0]] = fields[1:]
+		}
+	}
+
+	out, err = gitOutput(ctx, repoPath, "for-each-ref", "--format=%(refname:short)%00%(objectname)%00%(worktreepath)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []UnpushedBranch
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 3 {
+			continue
+		}
+		name, tip, worktree := fields[0], fields[1], fields[2]
+		if linked && name != head {
+			continue
+		}
+		if !linked && worktree != "" && name != head {
+			// Checked out in a linked worktree, which lists it
+			continue
+		}
+		if n := countReachable(unpushed, tip); n > 0 {
+			branches = append(branches, UnpushedBranch{Name: name, Commits: n})
+		}
+	}
+
+	return branches, nil
+}
+
+// countReachable counts the commits of graph reachable from tip, where graph
+// maps each commit to its parents
+func countReachable(graph map[string][]string, tip string) int {
+	seen := make(map[string]bool)
+	stack := []string{tip}
+	for len(stack) > 0 {
+		commit := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		parents, ok := graph[commit]
+		if !ok || seen[commit] {
+			continue
+		}
+		seen[commit] = true
+		stack = append(stack, parents...)
+	}
+	return len(seen)
+}
+
+// commitTime returns the committer date of a commit
+func commitTime(ctx context.Context, repoPath, rev string) (time.Time, error) {
+	output, err := gitOutput(ctx, repoPath, "show", "-s", "--format=%ct", rev)
+	if err != nil {
+		return time.Time{}, err
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid commit time %q: %w", strings.TrimSpace(output), err)
+	}
+	return time.Unix(seconds, 0), nil
+}
@@ -0,0 +1,150 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnpushedCommits(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "git-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create a bare repository to act as the remote and clone it
+	remoteDir := filepath.Join(tempDir, "remote.git")
+	runGit(t, tempDir, "init", "--bare", "--initial-branch=main", remoteDir)
+	repoDir := filepath.Join(tempDir, "clone")
+	runGit(t, tempDir, "clone", remoteDir, repoDir)
+	runGit(t, repoDir, "checkout", "-B", "main")
+
+	// Test case 1: Repository without commits
+	repo, err := CheckStatus(repoDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.Branch != "main" {
+		t.Errorf("Expected branch 'main', got '%s'", repo.Branch)
+	}
+	if repo.HasUnpushed() {
+		t.Error("Expected no unpushed work in an empty repository")
+	}
+
+	// Test case 2: Everything pushed
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "first")
+	runGit(t, repoDir, "push", "-u", "origin", "main")
+
+	repo, err = CheckStatus(repoDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.Upstream != "origin/main" {
+		t.Errorf("Expected upstream 'origin/main', got '%s'", repo.Upstream)
+	}
+	if repo.Ahead != 0 || repo.Behind != 0 {
+		t.Errorf("Expected to be in sync, got ahead %d, behind %d", repo.Ahead, repo.Behind)
+	}
+	if repo.HasUnpushed() {
+		t.Errorf("Expected no unpushed work, got %+v", repo.UnpushedBranches)
+	}
+
+	// Test case 3: Committed but not pushed, on the current and another branch
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "second")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "third")
+	runGit(t, repoDir, "branch", "feature")
+	runGit(t, repoDir, "checkout", "-b", "local-only")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "fourth")
+	runGit(t, repoDir, "checkout", "main")
+
+	repo, err = CheckStatus(repoDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.IsDirty() {
+		t.Error("Expected a clean working tree")
+	}
+	if repo.Ahead != 2 || repo.Behind != 0 {
+		t.Errorf("Expected ahead 2, behind 0, got ahead %d, behind %d", repo.Ahead, repo.Behind)
+	}
+	if !repo.HasUnpushed() {
+		t.Error("Expected unpushed work")
+	}
+
+	expected := []UnpushedBranch{
+		{Name: "feature", Commits: 2},
+		{Name: "local-only", Commits: 3},
+		{Name: "main", Commits: 2},
+	}
+	if len(repo.UnpushedBranches) != len(expected) {
+		t.Fatalf("Expected %d unpushed branches, got %+v", len(expected), repo.UnpushedBranches)
+	}
+	for i, want := range expected {
+		if repo.UnpushedBranches[i] != want {
+			t.Errorf("Expected %+v, got %+v", want, repo.UnpushedBranches[i])
+		}
+	}
+
+	// Test case 4: Behind the upstream after someone else pushed
+	otherDir := filepath.Join(tempDir, "other")
+	runGit(t, tempDir, "clone", remoteDir, otherDir)
+	runGit(t, otherDir, "commit", "--allow-empty", "-m", "upstream")
+	runGit(t, otherDir, "push", "origin", "main")
+	runGit(t, repoDir, "fetch")
+
+	repo, err = CheckStatus(repoDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.Ahead != 2 || repo.Behind != 1 {
+		t.Errorf("Expected ahead 2, behind 1, got ahead %d, behind %d", repo.Ahead, repo.Behind)
+	}
+
+	// Test case 5: Branches checked out in a linked worktree are listed
+	// there rather than on the main working tree
+	wtDir := filepath.Join(tempDir, "wt")
+	runGit(t, repoDir, "worktree", "add", wtDir, "local-only")
+
+	checks := []struct {
+		path     string
+		expected []UnpushedBranch
+	}{
+		{repoDir, []UnpushedBranch{{Name: "feature", Commits: 2}, {Name: "main", Commits: 2}}},
+		{wtDir, []UnpushedBranch{{Name: "local-only", Commits: 3}}},
+	}
+	for _, check := range checks {
+		repo, err = CheckStatus(check.path)
+		if err != nil {
+			t.Fatalf("CheckStatus failed: %v", err)
+		}
+		if len(repo.UnpushedBranches) != len(check.expected) {
+			t.Errorf("%s: expected %+v, got %+v", check.path, check.expected, repo.UnpushedBranches)
+			continue
+		}
+		for i, want := range check.expected {
+			if repo.UnpushedBranches[i] != want {
+				t.Errorf("%s: expected %+v, got %+v", check.path, want, repo.UnpushedBranches[i])
+			}
+		}
+	}
+
+	// Test case 6: A worktree on a detached HEAD has no branch of its own,
+	// so the main working tree lists them all
+	runGit(t, wtDir, "checkout", "--detach")
+	repo, err = CheckStatus(wtDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if len(repo.UnpushedBranches) != 0 {
+		t.Errorf("Expected no unpushed branches on a detached worktree, got %+v", repo.UnpushedBranches)
+	}
+	repo, err = CheckStatus(repoDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if len(repo.UnpushedBranches) != 3 {
+		t.Errorf("Expected the main working tree to list all 3 branches again, got %+v", repo.UnpushedBranches)
+	}
+}
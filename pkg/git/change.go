@@ -0,0 +1,191 @@
+package git
+
+import "fmt"
+
+// StatusCode is a single column of the two letter status reported by
+// git status for a path
+type StatusCode byte
+
+const (
+	StatusUnmodified  StatusCode = '.'
+	StatusModified    StatusCode = 'M'
+	StatusTypeChanged StatusCode = 'T'
+	StatusAdded       StatusCode = 'A'
+	StatusDeleted     StatusCode = 'D'
+	StatusRenamed     StatusCode = 'R'
+	StatusCopied      StatusCode = 'C'
+	StatusUnmerged    StatusCode = 'U'
+	StatusUntracked   StatusCode = '?'
+	StatusIgnored     StatusCode = '!'
+)
+
+// String returns the status letter
+func (c StatusCode) String() string {
+	return string(c)
+}
+
+// MarshalText encodes the status as its letter
+func (c StatusCode) MarshalText() ([]byte, error) {
+	return []byte{byte(c)}, nil
+}
+
+// UnmarshalText decodes a status letter
+func (c *StatusCode) UnmarshalText(text []byte) error {
+	if len(text) != 1 {
+		return fmt.Errorf("invalid status code %q", text)
+	}
+	*c = StatusCode(text[0])
+	return nil
+}
+
+// ChangeKind classifies a change
+type ChangeKind int
+
+const (
+	ChangeUnknown ChangeKind = iota
+	ChangeModified
+	ChangeTypeChanged
+	ChangeAdded
+	ChangeDeleted
+	ChangeRenamed
+	ChangeCopied
+	ChangeUnmerged
+	ChangeUntracked
+	ChangeIgnored
+)
+
+var changeKindNames = map[ChangeKind]string{
+	ChangeUnknown:     "unknown",
+	ChangeModified:    "modified",
+	ChangeTypeChanged: "type changed",
+	ChangeAdded:       "added",
+	ChangeDeleted:     "deleted",
+	ChangeRenamed:     "renamed",
+	ChangeCopied:      "copied",
+	ChangeUnmerged:    "unmerged",
+	ChangeUntracked:   "untracked",
+	ChangeIgnored:     "ignored",
+}
+
+// String returns the human-readable name of the kind
+func (k ChangeKind) String() string {
+	if name, ok := changeKindNames[k]; ok {
+		return name
+	}
+	return changeKindNames[ChangeUnknown]
+}
+
+// MarshalText encodes the kind as its name
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a kind name
+func (k *ChangeKind) UnmarshalText(text []byte) error {
+	for kind, name := range changeKindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid change kind %q", text)
+}
+
+// Change is a single changed path in a working tree
+type Change struct {
+	// Path is the path relative to the repository root
+	Path string `json:"path"`
+	// OrigPath is the source path of a rename or copy
+	OrigPath string `json:"orig_path,omitempty"`
+	// Index is the status of the path in the index (staged)
+	Index StatusCode `json:"index"`
+	// Worktree is the status of the path in the working tree (unstaged)
+	Worktree StatusCode `json:"worktree"`
+	// Kind summarizes both status columns
+	Kind ChangeKind `json:"kind"`
+	// Size is the size in bytes of an untracked regular file, or of the
+	// largest regular file in an untracked directory, measured when the
+	// status was checked; zero for other changes
+	Size int64 `json:"size,omitempty"`
+}
+
+// NewChange creates a Change from the two status columns and derives its kind
+func NewChange(index, worktree StatusCode, path, origPath string) Change {
+	return Change{
+		Path:     path,
+		OrigPath: origPath,
+		Index:    index,
+		Worktree: worktree,
+		Kind:     changeKind(index, worktree),
+	}
+}
+
+// Staged reports whether the change is recorded in the index
+func (c Change) Staged() bool {
+	switch c.Kind {
+	case ChangeUntracked, ChangeIgnored, ChangeUnmerged:
+		return false
+	}
+	return c.Index != StatusUnmodified
+}
+
+// Unstaged reports whether the working tree differs from the index
+func (c Change) Unstaged() bool {
+	switch c.Kind {
+	case ChangeUntracked, ChangeIgnored, ChangeUnmerged:
+		return false
+	}
+	return c.Worktree != StatusUnmodified
+}
+
+// String formats the change as "kind: path"
+func (c Change) String() string {
+	if c.OrigPath != "" {
+		return fmt.Sprintf("%s: %s -> %s", c.Kind, c.OrigPath, c.Path)
+	}
+	return fmt.Sprintf("%s: %s", c.Kind, c.Path)
+}
+
+// changeKind derives the kind of a change from its status columns. Staged
+// changes take precedence over unstaged ones.
+func changeKind(index, worktree StatusCode) ChangeKind {
+	switch {
+	case index == StatusUntracked && worktree == StatusUntracked:
+		return ChangeUntracked
+	case index == StatusIgnored && worktree == StatusIgnored:
+		return ChangeIgnored
+	case isUnmerged(index, worktree):
+		return ChangeUnmerged
+	}
+
+	code := index
+	if code == StatusUnmodified {
+		code = worktree
+	}
+
+	switch code {
+	case StatusModified:
+		return ChangeModified
+	case StatusTypeChanged:
+		return ChangeTypeChanged
+	case StatusAdded:
+		return ChangeAdded
+	case StatusDeleted:
+		return ChangeDeleted
+	case StatusRenamed:
+		return ChangeRenamed
+	case StatusCopied:
+		return ChangeCopied
+	default:
+		return ChangeUnknown
+	}
+}
+
+// isUnmerged reports whether the status columns describe a merge conflict
+func isUnmerged(index, worktree StatusCode) bool {
+	if index == StatusUnmerged || worktree == StatusUnmerged {
+		return true
+	}
+	// Both sides added or both sides deleted
+	return index == worktree && (index == StatusAdded || index == StatusDeleted)
+}
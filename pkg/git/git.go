@@ -1,26 +1,218 @@
 package git
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
+)
+
+// Kind describes how a working tree is attached to its Git directory
+type Kind string
+
+const (
+	// KindMain is a regular repository with its own .git directory
+	KindMain Kind = "main"
+	// KindWorktree is a linked working tree created by git worktree add
+	KindWorktree Kind = "worktree"
+	// KindSubmodule is a submodule checked out inside a superproject
+	KindSubmodule Kind = "submodule"
 )
 
 // Repository represents a Git repository
 type Repository struct {
 	Path    string
-	Changes []string
+	Kind    Kind
+	GitDir  string
+	Parent  string
+	Changes []Change
+
+	// Branch is the current branch, empty when HEAD is detached
+	Branch string
+	// Upstream is the upstream of the current branch, if any
+	Upstream string
+	// Ahead and Behind count commits relative to the upstream
+	Ahead  int
+	Behind int
+	// UnpushedBranches are local branches with commits not on any remote
+	UnpushedBranches []UnpushedBranch
+	// Stashes are the entries of the stash list, newest first
+	Stashes []Stash
+	// State is the operation in progress, if any
+	State State
+	// LastCommit is the committer date of HEAD, zero before the first commit
+	LastCommit time.Time
 }
 
-// IsGitRepo checks if a directory is a Git repository
+// Info describes where the Git data of a working tree lives
+type Info struct {
+	// Path is the working tree
+	Path string
+	// GitDir is the resolved Git directory of the working tree
+	GitDir string
+	// Kind tells whether this is a main repository, a worktree or a submodule
+	Kind Kind
+	// Parent is the main working tree of a worktree, or the working tree
+	// of the superproject of a submodule
+	Parent string
+}
+
+// ErrNotRepository is returned when a directory is not a Git working tree
+var ErrNotRepository = errors.New("not a git repository")
+
+// IsGitRepo checks if a directory is a Git repository. Both .git directories
+// and .git files pointing to a Git directory elsewhere are accepted.
 func IsGitRepo(path string) bool {
-	gitDir := filepath.Join(path, ".git")
-	info, err := os.Stat(gitDir)
+	_, err := Resolve(path)
+	return err == nil
+}
+
+// Resolve inspects the .git entry of a working tree and reports its kind.
+// A .git file ("gitdir: <path>") is followed to the Git directory it
+// points to, which is how linked worktrees and submodules are stored.
+func Resolve(path string) (*Info, error) {
+	dotGit := filepath.Join(path, ".git")
+	fi, err := os.Stat(dotGit)
+	if err != nil {
+		return nil, ErrNotRepository
+	}
+
+	if fi.IsDir() {
+		return &Info{Path: path, GitDir: dotGit, Kind: KindMain}, nil
+	}
+
+	gitDir, err := readGitFile(dotGit)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(gitDir); err != nil || !fi.IsDir() {
+		return nil, fmt.Errorf("%s: gitdir %s does not exist", dotGit, gitDir)
+	}
+
+	info := &Info{Path: path, GitDir: gitDir}
+
+	// Linked worktrees keep a commondir file pointing at the main .git
+	if common, err := readFirstLine(filepath.Join(gitDir, "commondir")); err == nil {
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		info.Kind = KindWorktree
+		info.Parent = workTreeOf(filepath.Clean(common))
+		return info, nil
+	}
+
+	// Submodules keep their Git directory in the modules directory of the
+	// closest repository above, their superproject. Anything else was
+	// created with --separate-git-dir and is a repository of its own.
+	info.Kind = KindMain
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		parent, err := Resolve(dir)
+		if err != nil {
+			continue
+		}
+		if isWithin(gitDir, filepath.Join(parent.GitDir, "modules")) {
+			info.Kind = KindSubmodule
+			info.Parent = dir
+		}
+		break
+	}
+
+	return info, nil
+}
+
+// isWithin reports whether path is below dir
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Worktrees returns the working trees of the linked worktrees of a main
+// Git directory, as recorded in its worktrees/*/gitdir files. Worktrees
+// whose directory no longer exists are left out.
+func Worktrees(gitDir string) ([]string, error) {
+	adminDir := filepath.Join(gitDir, "worktrees")
+	entries, err := os.ReadDir(adminDir)
 	if err != nil {
-		return false
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
-	return info.IsDir()
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(adminDir, entry.Name())
+		dotGit, err := readFirstLine(filepath.Join(dir, "gitdir"))
+		if err != nil {
+			continue
+		}
+		if !filepath.IsAbs(dotGit) {
+			dotGit = filepath.Join(dir, dotGit)
+		}
+		path := filepath.Dir(filepath.Clean(dotGit))
+		if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+			continue
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// readGitFile parses a .git file and returns the absolute Git directory
+func readGitFile(path string) (string, error) {
+	line, err := readFirstLine(path)
+	if err != nil {
+		return "", err
+	}
+
+	gitDir, ok := strings.CutPrefix(line, "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("%s: invalid gitfile format", path)
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+
+	return filepath.Clean(gitDir), nil
+}
+
+// readFirstLine returns the first line of a file without surrounding spaces
+func readFirstLine(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%s: empty file", path)
+	}
+
+	return strings.TrimSpace(sc.Text()), nil
+}
+
+// workTreeOf returns the working tree that owns a main Git directory
+func workTreeOf(gitDir string) string {
+	if filepath.Base(gitDir) == ".git" {
+		return filepath.Dir(gitDir)
+	}
+	// Bare repositories have no working tree of their own
+	return gitDir
 }
 
 // NewRepository creates a new Repository instance
@@ -30,83 +222,139 @@ func NewRepository(path string) *Repository {
 	}
 }
 
+// waitDelay bounds how long a killed Git command may keep its output open,
+// e.g. through a hook that spawned its own children
+const waitDelay = time.Second
+
 // CheckStatus checks the status of a Git repository
 func CheckStatus(repoPath string) (*Repository, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = repoPath
+	return CheckStatusContext(context.Background(), repoPath)
+}
 
-	output, err := cmd.Output()
+// CheckStatusContext is like CheckStatus but kills the Git commands it runs
+// when ctx is done
+func CheckStatusContext(ctx context.Context, repoPath string) (*Repository, error) {
+	output, err := gitOutput(ctx, repoPath, "status", "--porcelain=v2", "-z", "--branch")
 	if err != nil {
 		return nil, err
 	}
 
+	branch, changes, err := parseGitStatus(output)
+	if err != nil {
+		return nil, err
+	}
+	if err := measureUntracked(ctx, repoPath, changes); err != nil {
+		return nil, err
+	}
+
 	repo := &Repository{
 		Path:    repoPath,
-		Changes: parseGitStatus(string(output)),
+		Changes: changes,
 	}
+	repo.applyBranchStatus(branch)
 
-	return repo, nil
-}
+	if branch.oid != "" && branch.oid != "(initial)" {
+		repo.LastCommit, err = commitTime(ctx, repoPath, branch.oid)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-// parseGitStatus parses the output of git status --porcelain
-func parseGitStatus(output string) []string {
-	if output == "" {
-		return nil
+	if info, err := Resolve(repoPath); err == nil {
+		repo.Kind = info.Kind
+		repo.GitDir = info.GitDir
+		repo.Parent = info.Parent
+		repo.State = DetectState(info.GitDir, branch.head == "(detached)")
+	} else if branch.head == "(detached)" {
+		repo.State = StateDetached
 	}
 
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	changes := make([]string, 0, len(lines))
+	repo.UnpushedBranches, err = unpushedBranches(ctx, repoPath, branch.head, repo.Kind == KindWorktree)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, line := range lines {
-		if line == "" {
-			continue
+	// refs/stash is shared by all worktrees of a repository, so the stashes
+	// are only listed for the main working tree
+	if repo.Kind != KindWorktree {
+		repo.Stashes, err = ListStashesContext(ctx, repoPath)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		// Parse the status line
-		// Format: XY PATH
-		// X = staged changes
-		// Y = unstaged changes
-		// Both can be:
-		//   M = modified
-		//   A = added
-		//   D = deleted
-		//   R = renamed
-		//   C = copied
-		//   U = updated but unmerged
-		//   ? = untracked
-		//   ! = ignored
-		//   T = type change
-		//   X = unknown
-
-		status := line[:2]
-		path := strings.TrimSpace(line[3:])
-
-		var change string
+	return repo, nil
+}
+
+// measureUntracked records the size of the untracked regular files among
+// changes, so that reports do not depend on the working tree afterwards.
+// Git lists a new directory as a single entry, which gets the size of the
+// largest file inside it.
+func measureUntracked(ctx context.Context, repoPath string, changes []Change) error {
+	for i, change := range changes {
+		if change.Kind != ChangeUntracked {
+			continue
+		}
+		path := filepath.Join(repoPath, change.Path)
+		fi, err := os.Lstat(path)
 		switch {
-		case strings.HasPrefix(status, "M"):
-			change = "modified: " + path
-		case strings.HasPrefix(status, "A"):
-			change = "added: " + path
-		case strings.HasPrefix(status, "D"):
-			change = "deleted: " + path
-		case strings.HasPrefix(status, "R"):
-			change = "renamed: " + path
-		case strings.HasPrefix(status, "C"):
-			change = "copied: " + path
-		case strings.HasPrefix(status, "U"):
-			change = "unmerged: " + path
-		case strings.HasPrefix(status, "?"):
-			change = "untracked: " + path
-		case strings.HasPrefix(status, "!"):
-			change = "ignored: " + path
-		case strings.HasPrefix(status, "T"):
-			change = "type changed: " + path
-		default:
-			change = "unknown: " + path
-		}
-
-		changes = append(changes, change)
-	}
-
-	return changes
+		case err != nil:
+			continue
+		case fi.Mode().IsRegular():
+			changes[i].Size = fi.Size()
+		case fi.IsDir():
+			if changes[i].Size, err = largestFile(ctx, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// largestFile returns the size of the largest regular file below dir.
+// Nested repositories and unreadable paths are skipped.
+func largestFile(ctx context.Context, dir string) (int64, error) {
+	var largest int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil && fi.Size() > largest {
+			largest = fi.Size()
+		}
+		return nil
+	})
+	return largest, err
+}
+
+// gitOutput runs a Git command in a repository and returns its output. The
+// command is killed when ctx is done, in which case ctx.Err() is returned.
+func gitOutput(ctx context.Context, repoPath string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoPath
+	cmd.WaitDelay = waitDelay
+
+	output, err := cmd.Output()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		return "", err
+	}
+	return string(output), nil
 }
@@ -1,11 +1,14 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestIsGitRepo(t *testing.T) {
@@ -61,6 +64,9 @@ func TestCheckStatus(t *testing.T) {
 	if len(repo.Changes) != 0 {
 		t.Errorf("Expected no changes, got %d changes", len(repo.Changes))
 	}
+	if !repo.LastCommit.IsZero() {
+		t.Errorf("Expected no last commit before the first commit, got %v", repo.LastCommit)
+	}
 
 	// Test case 2: Create a file
 	testFile := filepath.Join(tempDir, "test.txt")
@@ -72,8 +78,8 @@ func TestCheckStatus(t *testing.T) {
 	if err != nil {
 		t.Errorf("CheckStatus failed: %v", err)
 	}
-	if len(repo.Changes) == 0 {
-		t.Error("Expected changes after creating file")
+	if len(repo.Changes) != 1 || repo.Changes[0].Size != 4 {
+		t.Errorf("Expected one untracked file of 4 bytes after creating file, got %+v", repo.Changes)
 	}
 
 	// Test case 3: Add a file
@@ -105,6 +111,9 @@ func TestCheckStatus(t *testing.T) {
 	if len(repo.Changes) != 0 {
 		t.Errorf("Expected no changes after commit, got %d changes", len(repo.Changes))
 	}
+	if age := time.Since(repo.LastCommit); age < 0 || age > time.Hour {
+		t.Errorf("Expected the last commit to be recent, got %v", repo.LastCommit)
+	}
 
 	// Test case 5: Modify a file
 	if err := os.WriteFile(testFile, []byte("modified"), 0644); err != nil {
@@ -137,49 +146,176 @@ func TestCheckStatus(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for non-existent directory")
 	}
+
+	// Test case 8: Cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CheckStatusContext(ctx, tempDir); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
 }
 
-func TestParseGitStatus(t *testing.T) {
-	// Test case 1: Empty output
-	changes := parseGitStatus("")
-	if len(changes) != 0 {
-		t.Errorf("Expected no changes for empty output, got %d changes", len(changes))
+func TestCheckStatusUntrackedSizes(t *testing.T) {
+	tempDir := t.TempDir()
+	runGit(t, tempDir, "init")
+
+	// Git lists the new directory as a single entry
+	files := map[string]int64{
+		"top.txt":               5,
+		"newdir/small.txt":      3,
+		"newdir/deep/huge.bin":  11 << 20,
+		"newdir/nested/.git/x":  20 << 20,
+		"newdir/nested/big.bin": 20 << 20,
+	}
+	for name, size := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		// Sparse files take no space on disk
+		if err := f.Truncate(size); err != nil {
+			t.Fatalf("Failed to grow file: %v", err)
+		}
+		f.Close()
 	}
 
-	// Test case 2: Modified file
-	output := "M  file.txt"
-	changes = parseGitStatus(output)
-	if len(changes) != 1 {
-		t.Errorf("Expected 1 change, got %d", len(changes))
+	repo, err := CheckStatus(tempDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	expected := map[string]int64{
+		"top.txt": 5,
+		// The nested repository is not part of the directory
+		"newdir/": 11 << 20,
+	}
+	if len(repo.Changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), repo.Changes)
 	}
-	if !strings.Contains(changes[0], "modified: file.txt") {
-		t.Errorf("Expected 'modified: file.txt', got '%s'", changes[0])
+	for _, change := range repo.Changes {
+		if want, ok := expected[change.Path]; !ok || change.Size != want {
+			t.Errorf("%s: expected size %d, got %d", change.Path, want, change.Size)
+		}
 	}
+}
 
-	// Test case 3: Added file
-	output = "A  new_file.txt"
-	changes = parseGitStatus(output)
-	if len(changes) != 1 {
-		t.Errorf("Expected 1 change, got %d", len(changes))
+// runGit runs a Git command in dir with a fixed identity
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=gus", "-c", "user.email=gus@example.com", "-c", "protocol.file.allow=always"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "git-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	tempDir, _ = filepath.EvalSymlinks(tempDir)
+
+	// Create a main repository with one commit
+	mainDir := filepath.Join(tempDir, "main")
+	libDir := filepath.Join(tempDir, "lib")
+	for _, dir := range []string{mainDir, libDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		runGit(t, dir, "init")
+		runGit(t, dir, "commit", "--allow-empty", "-m", "initial")
+	}
+
+	// Test case 1: Main repository
+	info, err := Resolve(mainDir)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if info.Kind != KindMain {
+		t.Errorf("Expected kind %q, got %q", KindMain, info.Kind)
 	}
-	if !strings.Contains(changes[0], "added: new_file.txt") {
-		t.Errorf("Expected 'added: new_file.txt', got '%s'", changes[0])
+	if info.GitDir != filepath.Join(mainDir, ".git") {
+		t.Errorf("Expected gitdir %s, got %s", filepath.Join(mainDir, ".git"), info.GitDir)
 	}
 
-	// Test case 4: Deleted file
-	output = "D  old_file.txt"
-	changes = parseGitStatus(output)
-	if len(changes) != 1 {
-		t.Errorf("Expected 1 change, got %d", len(changes))
+	// Test case 2: Linked worktree
+	wtDir := filepath.Join(tempDir, "main-wt")
+	runGit(t, mainDir, "worktree", "add", wtDir)
+
+	if !IsGitRepo(wtDir) {
+		t.Error("Expected true for linked worktree")
+	}
+	info, err = Resolve(wtDir)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if info.Kind != KindWorktree {
+		t.Errorf("Expected kind %q, got %q", KindWorktree, info.Kind)
+	}
+	if info.Parent != mainDir {
+		t.Errorf("Expected parent %s, got %s", mainDir, info.Parent)
+	}
+
+	// Test case 3: Submodule
+	runGit(t, mainDir, "submodule", "add", libDir, "lib")
+	subDir := filepath.Join(mainDir, "lib")
+
+	if !IsGitRepo(subDir) {
+		t.Error("Expected true for submodule")
+	}
+	info, err = Resolve(subDir)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if info.Kind != KindSubmodule {
+		t.Errorf("Expected kind %q, got %q", KindSubmodule, info.Kind)
+	}
+	if info.Parent != mainDir {
+		t.Errorf("Expected parent %s, got %s", mainDir, info.Parent)
+	}
+
+	repo, err := CheckStatus(subDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.Kind != KindSubmodule || repo.Parent != mainDir {
+		t.Errorf("Expected submodule of %s, got %s of %s", mainDir, repo.Kind, repo.Parent)
+	}
+
+	// Test case 4: Repository with a separate Git directory inside another
+	// repository, e.g. dotfiles in the home directory
+	sepDir := filepath.Join(mainDir, "dotfiles")
+	runGit(t, mainDir, "init", "--separate-git-dir", filepath.Join(tempDir, "dotfiles.git"), sepDir)
+
+	info, err = Resolve(sepDir)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if info.Kind != KindMain || info.Parent != "" {
+		t.Errorf("Expected kind %q without parent, got %q of %q", KindMain, info.Kind, info.Parent)
+	}
+
+	// Test case 5: Gitfile pointing nowhere
+	brokenDir := filepath.Join(tempDir, "broken")
+	if err := os.MkdirAll(brokenDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(brokenDir, ".git"), []byte("gitdir: ../nowhere\n"), 0644); err != nil {
+		t.Fatalf("Failed to write gitfile: %v", err)
 	}
-	if !strings.Contains(changes[0], "deleted: old_file.txt") {
-		t.Errorf("Expected 'deleted: old_file.txt', got '%s'", changes[0])
+	if IsGitRepo(brokenDir) {
+		t.Error("Expected false for gitfile pointing to a missing directory")
 	}
 
-	// Test case 5: Multiple changes
-	output = "M  file1.txt\nA  file2.txt\nD  file3.txt"
-	changes = parseGitStatus(output)
-	if len(changes) != 3 {
-		t.Errorf("Expected 3 changes, got %d", len(changes))
+	// Test case 6: Not a repository
+	if _, err := Resolve(tempDir); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("Expected ErrNotRepository, got %v", err)
 	}
 }
@@ -0,0 +1,75 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stash is an entry of the stash list
+type Stash struct {
+	// Index is n in stash@{n}
+	Index int `json:"index"`
+	// Message is the stash description, e.g. "WIP on main: 1234abc subject"
+	Message string `json:"message"`
+	// Time is when the stash was created
+	Time time.Time `json:"time"`
+}
+
+// Name returns the stash reference, e.g. stash@{0}
+func (s Stash) Name() string {
+	return fmt.Sprintf("stash@{%d}", s.Index)
+}
+
+// Age returns how long ago the stash was created relative to now
+func (s Stash) Age(now time.Time) time.Duration {
+	return now.Sub(s.Time)
+}
+
+// HasStashes reports whether the repository has stashed changes
+func (r *Repository) HasStashes() bool {
+	return len(r.Stashes) > 0
+}
+
+// ListStashes enumerates the stash entries of a repository, newest first
+func ListStashes(repoPath string) ([]Stash, error) {
+	return ListStashesContext(context.Background(), repoPath)
+}
+
+// ListStashesContext is like ListStashes but stops Git when ctx is done
+func ListStashesContext(ctx context.Context, repoPath string) ([]Stash, error) {
+	output, err := gitOutput(ctx, repoPath, "stash", "list", "--format=%ct%x00%gs")
+	if err != nil {
+		return nil, err
+	}
+	return parseStashList(output)
+}
+
+// parseStashList parses the output of git stash list --format=%ct%x00%gs.
+// Entries are listed newest first, so the line number is the stash index.
+func parseStashList(output string) ([]Stash, error) {
+	var stashes []Stash
+	for i, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+
+		ts, message, ok := strings.Cut(line, "\x00")
+		if !ok {
+			return nil, fmt.Errorf("malformed stash entry %q", line)
+		}
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed stash time %q", ts)
+		}
+
+		stashes = append(stashes, Stash{
+			Index:   i,
+			Message: message,
+			Time:    time.Unix(sec, 0),
+		})
+	}
+	return stashes, nil
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseStashList(t *testing.T) {
+	// Test case 1: Empty output
+	stashes, err := parseStashList("")
+	if err != nil {
+		t.Fatalf("parseStashList failed: %v", err)
+	}
+	if len(stashes) != 0 {
+		t.Errorf("Expected no stashes, got %d", len(stashes))
+	}
+
+	// Test case 2: Several entries, newest first
+	output := "1700000200\x00WIP on main: 1234abc subject\n1700000100\x00On feature: experiment\n"
+	stashes, err = parseStashList(output)
+	if err != nil {
+		t.Fatalf("parseStashList failed: %v", err)
+	}
+	expected := []Stash{
+		{Index: 0, Message: "WIP on main: 1234abc subject", Time: time.Unix(1700000200, 0)},
+		{Index: 1, Message: "On feature: experiment", Time: time.Unix(1700000100, 0)},
+	}
+	if len(stashes) != len(expected) {
+		t.Fatalf("Expected %d stashes, got %d", len(expected), len(stashes))
+	}
+	for i, want := range expected {
+		if stashes[i] != want {
+			t.Errorf("Expected %+v, got %+v", want, stashes[i])
+		}
+	}
+	if name := stashes[1].Name(); name != "stash@{1}" {
+		t.Errorf("Expected name 'stash@{1}', got '%s'", name)
+	}
+	if age := stashes[0].Age(time.Unix(1700003800, 0)); age != time.Hour {
+		t.Errorf("Expected age of 1h, got %v", age)
+	}
+
+	// Test case 3: Malformed entries
+	for _, output := range []string{"no separator\n", "soon\x00message\n"} {
+		if _, err := parseStashList(output); err == nil {
+			t.Errorf("Expected error for %q", output)
+		}
+	}
+}
+
+func TestListStashes(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "git-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	runGit(t, tempDir, "init")
+	testFile := filepath.Join(tempDir, "test.txt")
+	if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	runGit(t, tempDir, "add", "test.txt")
+	runGit(t, tempDir, "commit", "-m", "initial")
+
+	// Test case 1: No stashes
+	repo, err := CheckStatus(tempDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.HasStashes() {
+		t.Errorf("Expected no stashes, got %+v", repo.Stashes)
+	}
+
+	// Test case 2: Stashed changes leave a clean working tree behind
+	for _, message := range []string{"first", "second"} {
+		if err := os.WriteFile(testFile, []byte(message), 0644); err != nil {
+			t.Fatalf("Failed to modify test file: %v", err)
+		}
+		runGit(t, tempDir, "stash", "push", "-m", message)
+	}
+
+	repo, err = CheckStatus(tempDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.IsDirty() {
+		t.Error("Expected a clean working tree")
+	}
+	if len(repo.Stashes) != 2 {
+		t.Fatalf("Expected 2 stashes, got %d", len(repo.Stashes))
+	}
+	if repo.Stashes[0].Message != "On master: second" && repo.Stashes[0].Message != "On main: second" {
+		t.Errorf("Unexpected newest stash message %q", repo.Stashes[0].Message)
+	}
+	if repo.Stashes[1].Index != 1 {
+		t.Errorf("Expected index 1, got %d", repo.Stashes[1].Index)
+	}
+	if age := repo.Stashes[0].Age(time.Now()); age < 0 || age > time.Hour {
+		t.Errorf("Unexpected stash age %v", age)
+	}
+
+	// Test case 3: Linked worktrees share refs/stash with the main working
+	// tree, which alone lists the stashes
+	wtDir := filepath.Join(t.TempDir(), "wt")
+	runGit(t, tempDir, "worktree", "add", wtDir)
+
+	repo, err = CheckStatus(wtDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.Kind != KindWorktree || repo.HasStashes() {
+		t.Errorf("Expected a worktree without stashes, got %s with %+v", repo.Kind, repo.Stashes)
+	}
+}
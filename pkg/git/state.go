@@ -0,0 +1,94 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// State is an operation in progress in a working tree
+type State string
+
+const (
+	// StateNone means no operation is in progress and HEAD is on a branch
+	StateNone State = ""
+	// StateMerging is a merge waiting for conflicts to be resolved
+	StateMerging State = "merging"
+	// StateRebasing is an interrupted rebase
+	StateRebasing State = "rebasing"
+	// StateApplying is an interrupted git am
+	StateApplying State = "applying"
+	// StateCherryPicking is an interrupted cherry-pick
+	StateCherryPicking State = "cherry-picking"
+	// StateReverting is an interrupted revert
+	StateReverting State = "reverting"
+	// StateBisecting is a bisect session that was not reset
+	StateBisecting State = "bisecting"
+	// StateDetached is a HEAD that points at a commit instead of a branch
+	StateDetached State = "detached"
+)
+
+// States lists every state other than StateNone, in order of precedence
+var States = []State{
+	StateMerging,
+	StateRebasing,
+	StateApplying,
+	StateCherryPicking,
+	StateReverting,
+	StateBisecting,
+	StateDetached,
+}
+
+// ParseState converts a state name to a State
+func ParseState(name string) (State, error) {
+	for _, state := range States {
+		if string(state) == name {
+			return state, nil
+		}
+	}
+
+	names := make([]string, len(States))
+	for i, state := range States {
+		names[i] = string(state)
+	}
+	return StateNone, fmt.Errorf("unknown state %q (valid states: %s)", name, strings.Join(names, ", "))
+}
+
+// InProgress reports whether the repository is in the middle of an
+// operation or on a detached HEAD
+func (r *Repository) InProgress() bool {
+	return r.State != StateNone
+}
+
+// DetectState inspects a Git directory for the marker files left behind by
+// interrupted operations. When several apply, the first one in States wins;
+// detached only applies when no operation is in progress.
+func DetectState(gitDir string, detached bool) State {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+
+	switch {
+	case exists("MERGE_HEAD"):
+		return StateMerging
+	case exists("rebase-merge"):
+		return StateRebasing
+	case exists("rebase-apply"):
+		// git am and the apply backend of git rebase share this directory
+		if exists(filepath.Join("rebase-apply", "applying")) {
+			return StateApplying
+		}
+		return StateRebasing
+	case exists("CHERRY_PICK_HEAD"):
+		return StateCherryPicking
+	case exists("REVERT_HEAD"):
+		return StateReverting
+	case exists("BISECT_LOG"):
+		return StateBisecting
+	case detached:
+		return StateDetached
+	}
+	return StateNone
+}
@@ -0,0 +1,133 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectState(t *testing.T) {
+	tests := []struct {
+		name     string
+		markers  []string
+		detached bool
+		want     State
+	}{
+		{"no operation", nil, false, StateNone},
+		{"detached HEAD", nil, true, StateDetached},
+		{"merge", []string{"MERGE_HEAD"}, false, StateMerging},
+		{"interactive rebase", []string{"rebase-merge/"}, true, StateRebasing},
+		{"apply rebase", []string{"rebase-apply/"}, true, StateRebasing},
+		{"git am", []string{"rebase-apply/", "rebase-apply/applying"}, false, StateApplying},
+		{"cherry-pick", []string{"CHERRY_PICK_HEAD"}, false, StateCherryPicking},
+		{"revert", []string{"REVERT_HEAD"}, false, StateReverting},
+		{"bisect", []string{"BISECT_LOG"}, true, StateBisecting},
+		{"cherry-pick during rebase", []string{"rebase-merge/", "CHERRY_PICK_HEAD"}, true, StateRebasing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitDir := t.TempDir()
+			for _, marker := range tt.markers {
+				path := filepath.Join(gitDir, filepath.FromSlash(marker))
+				var err error
+				if marker[len(marker)-1] == '/' {
+					err = os.MkdirAll(path, 0755)
+				} else {
+					err = os.WriteFile(path, nil, 0644)
+				}
+				if err != nil {
+					t.Fatalf("Failed to create marker %s: %v", marker, err)
+				}
+			}
+
+			if got := DetectState(gitDir, tt.detached); got != tt.want {
+				t.Errorf("Expected state %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseState(t *testing.T) {
+	for _, state := range States {
+		got, err := ParseState(string(state))
+		if err != nil || got != state {
+			t.Errorf("ParseState(%q): expected %q, got %q (%v)", state, state, got, err)
+		}
+	}
+
+	if _, err := ParseState("sleeping"); err == nil {
+		t.Error("Expected error for unknown state")
+	}
+}
+
+func TestCheckStatusState(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "git-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	runGit(t, tempDir, "init", "--initial-branch=main")
+	testFile := filepath.Join(tempDir, "test.txt")
+	if err := os.WriteFile(testFile, []byte("base\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	runGit(t, tempDir, "add", "test.txt")
+	runGit(t, tempDir, "commit", "-m", "base")
+
+	// Test case 1: On a branch
+	repo, err := CheckStatus(tempDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.State != StateNone || repo.InProgress() {
+		t.Errorf("Expected no state, got %q", repo.State)
+	}
+
+	// Test case 2: Detached HEAD
+	runGit(t, tempDir, "checkout", "--detach")
+	repo, err = CheckStatus(tempDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.State != StateDetached || repo.Branch != "" {
+		t.Errorf("Expected detached HEAD without branch, got %q on %q", repo.State, repo.Branch)
+	}
+	runGit(t, tempDir, "checkout", "main")
+
+	// Test case 3: Merge with conflicts
+	runGit(t, tempDir, "checkout", "-b", "other")
+	if err := os.WriteFile(testFile, []byte("other\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+	runGit(t, tempDir, "commit", "-am", "other")
+	runGit(t, tempDir, "checkout", "main")
+	if err := os.WriteFile(testFile, []byte("main\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+	runGit(t, tempDir, "commit", "-am", "main")
+
+	cmd := exec.Command("git", "-c", "user.name=gus", "-c", "user.email=gus@example.com", "merge", "other")
+	cmd.Dir = tempDir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "CONFLICT") {
+		t.Fatalf("Expected merge conflict, got %v\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, ".git", "MERGE_HEAD")); err != nil {
+		t.Fatalf("Expected a merge in progress: %v", err)
+	}
+
+	repo, err = CheckStatus(tempDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if repo.State != StateMerging {
+		t.Errorf("Expected state %q, got %q", StateMerging, repo.State)
+	}
+	if len(repo.Changes) != 1 || repo.Changes[0].Kind != ChangeUnmerged {
+		t.Errorf("Expected one unmerged change, got %+v", repo.Changes)
+	}
+}
@@ -0,0 +1,137 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// branchStatus holds the branch headers of git status --branch
+type branchStatus struct {
+	// oid is the current commit, or "(initial)" before the first commit
+	oid string
+	// head is the current branch, or "(detached)"
+	head string
+	// upstream is the upstream branch, if one is configured
+	upstream string
+	// ahead and behind are only known when the upstream exists
+	ahead, behind  int
+	hasAheadBehind bool
+}
+
+// parseGitStatus parses the output of git status --porcelain=v2 -z --branch.
+// Entries are NUL terminated, so paths are taken verbatim without quoting.
+//
+// The entry formats are:
+//
+//	# branch.<header> <value>
+//	1 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <path>
+//	2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <X><score> <path> NUL <origPath>
+//	u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+//	? <path>
+//	! <path>
+func parseGitStatus(output string) (branchStatus, []Change, error) {
+	var (
+		branch  branchStatus
+		changes []Change
+	)
+
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if entry == "" {
+			continue
+		}
+
+		switch entry[0] {
+		case '#':
+			if err := parseBranchHeader(&branch, entry); err != nil {
+				return branch, nil, err
+			}
+
+		case '1':
+			fields := strings.SplitN(entry, " ", 9)
+			if len(fields) != 9 {
+				return branch, nil, fmt.Errorf("malformed status entry %q", entry)
+			}
+			index, worktree, err := parseXY(fields[1])
+			if err != nil {
+				return branch, nil, err
+			}
+			changes = append(changes, NewChange(index, worktree, fields[8], ""))
+
+		case '2':
+			fields := strings.SplitN(entry, " ", 10)
+			if len(fields) != 10 || i+1 >= len(entries) {
+				return branch, nil, fmt.Errorf("malformed status entry %q", entry)
+			}
+			index, worktree, err := parseXY(fields[1])
+			if err != nil {
+				return branch, nil, err
+			}
+			// The original path is the next NUL separated entry
+			i++
+			changes = append(changes, NewChange(index, worktree, fields[9], entries[i]))
+
+		case 'u':
+			fields := strings.SplitN(entry, " ", 11)
+			if len(fields) != 11 {
+				return branch, nil, fmt.Errorf("malformed status entry %q", entry)
+			}
+			index, worktree, err := parseXY(fields[1])
+			if err != nil {
+				return branch, nil, err
+			}
+			changes = append(changes, NewChange(index, worktree, fields[10], ""))
+
+		case '?', '!':
+			if len(entry) < 3 || entry[1] != ' ' {
+				return branch, nil, fmt.Errorf("malformed status entry %q", entry)
+			}
+			code := StatusCode(entry[0])
+			changes = append(changes, NewChange(code, code, entry[2:], ""))
+
+		default:
+			return branch, nil, fmt.Errorf("unknown status entry %q", entry)
+		}
+	}
+
+	return branch, changes, nil
+}
+
+// parseBranchHeader parses a "# branch.<name> <value>" header line. Other
+// headers are ignored.
+func parseBranchHeader(branch *branchStatus, line string) error {
+	name, value, _ := strings.Cut(strings.TrimPrefix(line, "# "), " ")
+
+	switch name {
+	case "branch.oid":
+		branch.oid = value
+	case "branch.head":
+		branch.head = value
+	case "branch.upstream":
+		branch.upstream = value
+	case "branch.ab":
+		var ahead, behind string
+		if _, err := fmt.Sscan(value, &ahead, &behind); err != nil {
+			return fmt.Errorf("malformed branch.ab header %q", line)
+		}
+		a, err1 := strconv.Atoi(strings.TrimPrefix(ahead, "+"))
+		b, err2 := strconv.Atoi(strings.TrimPrefix(behind, "-"))
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("malformed branch.ab header %q", line)
+		}
+		branch.ahead, branch.behind, branch.hasAheadBehind = a, b, true
+	}
+
+	return nil
+}
+
+// parseXY splits the two letter status field into its index and worktree
+// columns
+func parseXY(xy string) (StatusCode, StatusCode, error) {
+	if len(xy) != 2 {
+		return 0, 0, fmt.Errorf("malformed status field %q", xy)
+	}
+	return StatusCode(xy[0]), StatusCode(xy[1]), nil
+}
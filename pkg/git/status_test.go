@@ -0,0 +1,252 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ordinary returns a porcelain v2 entry for a changed tracked path
+func ordinary(xy, path string) string {
+	return "1 " + xy + " N... 100644 100644 100644 1111111111111111111111111111111111111111 2222222222222222222222222222222222222222 " + path + "\x00"
+}
+
+// renamed returns a porcelain v2 entry for a renamed or copied path
+func renamed(xy, score, path, origPath string) string {
+	return "2 " + xy + " N... 100644 100644 100644 1111111111111111111111111111111111111111 2222222222222222222222222222222222222222 " + score + " " + path + "\x00" + origPath + "\x00"
+}
+
+// unmerged returns a porcelain v2 entry for a path with a merge conflict
+func unmerged(xy, path string) string {
+	return "u " + xy + " N... 100644 100644 100644 100644 1111111111111111111111111111111111111111 2222222222222222222222222222222222222222 3333333333333333333333333333333333333333 " + path + "\x00"
+}
+
+func TestParseGitStatus(t *testing.T) {
+	// Test case 1: Empty output
+	_, changes, err := parseGitStatus("")
+	if err != nil {
+		t.Fatalf("parseGitStatus failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes for empty output, got %d changes", len(changes))
+	}
+
+	// Test case 2: Every XY combination documented by git status
+	tests := []struct {
+		name     string
+		entry    string
+		change   Change
+		staged   bool
+		unstaged bool
+	}{
+		// Not updated in the index
+		{"worktree modified", ordinary(".M", "a.txt"), Change{Path: "a.txt", Index: '.', Worktree: 'M', Kind: ChangeModified}, false, true},
+		{"worktree type changed", ordinary(".T", "a.txt"), Change{Path: "a.txt", Index: '.', Worktree: 'T', Kind: ChangeTypeChanged}, false, true},
+		{"worktree deleted", ordinary(".D", "a.txt"), Change{Path: "a.txt", Index: '.', Worktree: 'D', Kind: ChangeDeleted}, false, true},
+		{"intent to add", ordinary(".A", "a.txt"), Change{Path: "a.txt", Index: '.', Worktree: 'A', Kind: ChangeAdded}, false, true},
+
+		// Updated in the index
+		{"index modified", ordinary("M.", "a.txt"), Change{Path: "a.txt", Index: 'M', Worktree: '.', Kind: ChangeModified}, true, false},
+		{"index modified, worktree modified", ordinary("MM", "a.txt"), Change{Path: "a.txt", Index: 'M', Worktree: 'M', Kind: ChangeModified}, true, true},
+		{"index modified, worktree type changed", ordinary("MT", "a.txt"), Change{Path: "a.txt", Index: 'M', Worktree: 'T', Kind: ChangeModified}, true, true},
+		{"index modified, worktree deleted", ordinary("MD", "a.txt"), Change{Path: "a.txt", Index: 'M', Worktree: 'D', Kind: ChangeModified}, true, true},
+
+		// Type changed in the index
+		{"index type changed", ordinary("T.", "a.txt"), Change{Path: "a.txt", Index: 'T', Worktree: '.', Kind: ChangeTypeChanged}, true, false},
+		{"index type changed, worktree modified", ordinary("TM", "a.txt"), Change{Path: "a.txt", Index: 'T', Worktree: 'M', Kind: ChangeTypeChanged}, true, true},
+		{"index type changed, worktree type changed", ordinary("TT", "a.txt"), Change{Path: "a.txt", Index: 'T', Worktree: 'T', Kind: ChangeTypeChanged}, true, true},
+		{"index type changed, worktree deleted", ordinary("TD", "a.txt"), Change{Path: "a.txt", Index: 'T', Worktree: 'D', Kind: ChangeTypeChanged}, true, true},
+
+		// Added to the index
+		{"index added", ordinary("A.", "a.txt"), Change{Path: "a.txt", Index: 'A', Worktree: '.', Kind: ChangeAdded}, true, false},
+		{"index added, worktree modified", ordinary("AM", "a.txt"), Change{Path: "a.txt", Index: 'A', Worktree: 'M', Kind: ChangeAdded}, true, true},
+		{"index added, worktree type changed", ordinary("AT", "a.txt"), Change{Path: "a.txt", Index: 'A', Worktree: 'T', Kind: ChangeAdded}, true, true},
+		{"index added, worktree deleted", ordinary("AD", "a.txt"), Change{Path: "a.txt", Index: 'A', Worktree: 'D', Kind: ChangeAdded}, true, true},
+
+		// Deleted from the index
+		{"index deleted", ordinary("D.", "a.txt"), Change{Path: "a.txt", Index: 'D', Worktree: '.', Kind: ChangeDeleted}, true, false},
+
+		// Renamed in the index
+		{"index renamed", renamed("R.", "R100", "new.txt", "old.txt"), Change{Path: "new.txt", OrigPath: "old.txt", Index: 'R', Worktree: '.', Kind: ChangeRenamed}, true, false},
+		{"index renamed, worktree modified", renamed("RM", "R090", "new.txt", "old.txt"), Change{Path: "new.txt", OrigPath: "old.txt", Index: 'R', Worktree: 'M', Kind: ChangeRenamed}, true, true},
+		{"index renamed, worktree type changed", renamed("RT", "R100", "new.txt", "old.txt"), Change{Path: "new.txt", OrigPath: "old.txt", Index: 'R', Worktree: 'T', Kind: ChangeRenamed}, true, true},
+		{"index renamed, worktree deleted", renamed("RD", "R100", "new.txt", "old.txt"), Change{Path: "new.txt", OrigPath: "old.txt", Index: 'R', Worktree: 'D', Kind: ChangeRenamed}, true, true},
+
+		// Copied in the index
+		{"index copied", renamed("C.", "C100", "copy.txt", "orig.txt"), Change{Path: "copy.txt", OrigPath: "orig.txt", Index: 'C', Worktree: '.', Kind: ChangeCopied}, true, false},
+		{"index copied, worktree modified", renamed("CM", "C075", "copy.txt", "orig.txt"), Change{Path: "copy.txt", OrigPath: "orig.txt", Index: 'C', Worktree: 'M', Kind: ChangeCopied}, true, true},
+		{"index copied, worktree type changed", renamed("CT", "C100", "copy.txt", "orig.txt"), Change{Path: "copy.txt", OrigPath: "orig.txt", Index: 'C', Worktree: 'T', Kind: ChangeCopied}, true, true},
+		{"index copied, worktree deleted", renamed("CD", "C100", "copy.txt", "orig.txt"), Change{Path: "copy.txt", OrigPath: "orig.txt", Index: 'C', Worktree: 'D', Kind: ChangeCopied}, true, true},
+
+		// Renamed or copied in the work tree
+		{"worktree renamed", renamed(".R", "R100", "new.txt", "old.txt"), Change{Path: "new.txt", OrigPath: "old.txt", Index: '.', Worktree: 'R', Kind: ChangeRenamed}, false, true},
+		{"worktree copied", renamed(".C", "C100", "copy.txt", "orig.txt"), Change{Path: "copy.txt", OrigPath: "orig.txt", Index: '.', Worktree: 'C', Kind: ChangeCopied}, false, true},
+
+		// Unmerged
+		{"both deleted", unmerged("DD", "a.txt"), Change{Path: "a.txt", Index: 'D', Worktree: 'D', Kind: ChangeUnmerged}, false, false},
+		{"added by us", unmerged("AU", "a.txt"), Change{Path: "a.txt", Index: 'A', Worktree: 'U', Kind: ChangeUnmerged}, false, false},
+		{"deleted by them", unmerged("UD", "a.txt"), Change{Path: "a.txt", Index: 'U', Worktree: 'D', Kind: ChangeUnmerged}, false, false},
+		{"added by them", unmerged("UA", "a.txt"), Change{Path: "a.txt", Index: 'U', Worktree: 'A', Kind: ChangeUnmerged}, false, false},
+		{"deleted by us", unmerged("DU", "a.txt"), Change{Path: "a.txt", Index: 'D', Worktree: 'U', Kind: ChangeUnmerged}, false, false},
+		{"both added", unmerged("AA", "a.txt"), Change{Path: "a.txt", Index: 'A', Worktree: 'A', Kind: ChangeUnmerged}, false, false},
+		{"both modified", unmerged("UU", "a.txt"), Change{Path: "a.txt", Index: 'U', Worktree: 'U', Kind: ChangeUnmerged}, false, false},
+
+		// Untracked and ignored
+		{"untracked", "? a.txt\x00", Change{Path: "a.txt", Index: '?', Worktree: '?', Kind: ChangeUntracked}, false, false},
+		{"ignored", "! a.txt\x00", Change{Path: "a.txt", Index: '!', Worktree: '!', Kind: ChangeIgnored}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, changes, err := parseGitStatus(tt.entry)
+			if err != nil {
+				t.Fatalf("parseGitStatus failed: %v", err)
+			}
+			if len(changes) != 1 {
+				t.Fatalf("Expected 1 change, got %d", len(changes))
+			}
+			if changes[0] != tt.change {
+				t.Errorf("Expected %+v, got %+v", tt.change, changes[0])
+			}
+			if changes[0].Staged() != tt.staged {
+				t.Errorf("Expected staged=%v", tt.staged)
+			}
+			if changes[0].Unstaged() != tt.unstaged {
+				t.Errorf("Expected unstaged=%v", tt.unstaged)
+			}
+		})
+	}
+}
+
+func TestParseGitStatusPaths(t *testing.T) {
+	// Paths are taken verbatim: spaces, unicode, quotes, arrows and newlines
+	paths := []string{
+		"with space.txt",
+		"ünïcødé/日本語.txt",
+		`"quoted".txt`,
+		"a -> b.txt",
+		"line\nbreak.txt",
+	}
+
+	var output string
+	for _, path := range paths {
+		output += ordinary(".M", path)
+	}
+	output += renamed("R.", "R100", "new name.txt", "old name.txt")
+
+	_, changes, err := parseGitStatus(output)
+	if err != nil {
+		t.Fatalf("parseGitStatus failed: %v", err)
+	}
+	if len(changes) != len(paths)+1 {
+		t.Fatalf("Expected %d changes, got %d", len(paths)+1, len(changes))
+	}
+	for i, path := range paths {
+		if changes[i].Path != path {
+			t.Errorf("Expected path %q, got %q", path, changes[i].Path)
+		}
+	}
+	rename := changes[len(paths)]
+	if rename.Path != "new name.txt" || rename.OrigPath != "old name.txt" {
+		t.Errorf("Expected rename from %q to %q, got %+v", "old name.txt", "new name.txt", rename)
+	}
+	if s := rename.String(); s != "renamed: old name.txt -> new name.txt" {
+		t.Errorf("Unexpected text form %q", s)
+	}
+}
+
+func TestParseGitStatusBranch(t *testing.T) {
+	output := "# branch.oid 1111111111111111111111111111111111111111\x00" +
+		"# branch.head main\x00" +
+		"# branch.upstream origin/main\x00" +
+		"# branch.ab +2 -3\x00" +
+		"# stash 1\x00" +
+		ordinary(".M", "a.txt")
+
+	branch, changes, err := parseGitStatus(output)
+	if err != nil {
+		t.Fatalf("parseGitStatus failed: %v", err)
+	}
+	if len(changes) != 1 {
+		t.Errorf("Expected 1 change, got %d", len(changes))
+	}
+
+	expected := branchStatus{
+		oid:            "1111111111111111111111111111111111111111",
+		head:           "main",
+		upstream:       "origin/main",
+		ahead:          2,
+		behind:         3,
+		hasAheadBehind: true,
+	}
+	if branch != expected {
+		t.Errorf("Expected %+v, got %+v", expected, branch)
+	}
+}
+
+func TestParseGitStatusMalformed(t *testing.T) {
+	tests := []string{
+		"1 M. N...\x00",
+		"2 R. N... 100644 100644 100644 1111 2222 R100 new.txt",
+		"u UU a.txt\x00",
+		"?\x00",
+		"x unknown\x00",
+		"1 M N... 100644 100644 100644 1111 2222 a.txt\x00",
+		"# branch.ab two three\x00",
+	}
+
+	for _, output := range tests {
+		if _, _, err := parseGitStatus(output); err == nil {
+			t.Errorf("Expected error for %q", output)
+		}
+	}
+}
+
+func TestCheckStatusPaths(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "git-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	runGit(t, tempDir, "init")
+	for _, name := range []string{"old name.txt", "tracked.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	runGit(t, tempDir, "add", ".")
+	runGit(t, tempDir, "commit", "-m", "initial")
+
+	// Rename a file, modify another without staging and add an odd name
+	runGit(t, tempDir, "mv", "old name.txt", "new name.txt")
+	if err := os.WriteFile(filepath.Join(tempDir, "tracked.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "ünïcødé file.txt"), []byte("new"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	repo, err := CheckStatus(tempDir)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+
+	var got []string
+	for _, change := range repo.Changes {
+		got = append(got, change.String())
+	}
+	expected := []string{
+		"renamed: old name.txt -> new name.txt",
+		"modified: tracked.txt",
+		"untracked: ünïcødé file.txt",
+	}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if !repo.Changes[0].Staged() || repo.Changes[1].Staged() || !repo.Changes[1].Unstaged() {
+		t.Errorf("Unexpected staged/unstaged state: %+v", repo.Changes)
+	}
+}
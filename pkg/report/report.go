@@ -0,0 +1,56 @@
+// Package report defines the result of a scan, shared by the core package
+// that produces it and the formatters that render it.
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+	"github.com/nguyendangminh/gus/pkg/scanner"
+)
+
+// Report is the result of a scan
+type Report struct {
+	// Root is the absolute path that was scanned
+	Root string
+	// Repositories are the repositories reported by the scan, in the order
+	// they were found
+	Repositories []*git.Repository
+	// Clean are the repositories that were checked but not reported
+	Clean []*git.Repository
+	// Warnings are paths that were skipped because they could not be read
+	Warnings []scanner.Warning
+	// Errors are repositories whose status could not be checked
+	Errors []*RepoError
+	// StartTime is when the scan started
+	StartTime time.Time
+	// Duration is how long the scan took
+	Duration time.Duration
+	// Interrupted is set when the scan was cancelled or ran past its
+	// deadline, so that the report only covers part of the tree
+	Interrupted bool
+}
+
+// Total returns the number of repositories found by the scan
+func (r *Report) Total() int {
+	return len(r.Repositories) + len(r.Clean) + len(r.Errors)
+}
+
+// RepoError records a repository whose status could not be checked
+type RepoError struct {
+	Path string
+	Err  error
+	// TimedOut is set when the check was stopped by the per-repository timeout
+	TimedOut bool
+}
+
+// Error implements the error interface
+func (e *RepoError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *RepoError) Unwrap() error {
+	return e.Err
+}
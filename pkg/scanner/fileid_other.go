@@ -0,0 +1,22 @@
+//go:build !unix
+
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// deviceID is not available on this platform
+func deviceID(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// fileKey identifies a directory by its resolved path, as device and inode
+// numbers are not available on this platform
+func fileKey(path string, info os.FileInfo) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// deviceID returns the device a file resides on
+func deviceID(info os.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
+
+// fileKey identifies a directory by device and inode so that the same
+// directory reached through different symbolic links is recognized
+func fileKey(path string, info os.FileInfo) string {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return path
+	}
+	return fmt.Sprintf("%d:%d", uint64(st.Dev), uint64(st.Ino))
+}
@@ -0,0 +1,165 @@
+package scanner
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the per-directory ignore file
+const IgnoreFileName = ".gusignore"
+
+// DefaultExcludes are directories that never contain repositories worth
+// reporting but are expensive to walk
+var DefaultExcludes = []string{
+	"node_modules/",
+	"bower_components/",
+	"vendor/",
+	".cache/",
+	".venv/",
+	"__pycache__/",
+	".tox/",
+	".gradle/",
+	".terraform/",
+}
+
+// DefaultIgnoreFile returns the location of the global ignore file in the
+// user configuration directory, or an empty string if it is unknown
+func DefaultIgnoreFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gus", "ignore")
+}
+
+// ignoreRule is a single gitignore-style pattern
+type ignoreRule struct {
+	// base is the directory the pattern is relative to
+	base string
+	// pattern is the slash separated glob without !, leading and trailing /
+	pattern string
+	// negate re-includes paths matched by earlier rules
+	negate bool
+	// anchored patterns match the path relative to base instead of the name
+	anchored bool
+	// dirOnly patterns only match directories
+	dirOnly bool
+}
+
+// newIgnoreRule parses a pattern relative to base. It returns false for
+// blank lines and comments.
+func newIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.HasPrefix(line, "/") {
+		rule.anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	rule.pattern = line
+	return rule, true
+}
+
+// match reports whether the rule matches a path below its base
+func (r ignoreRule) match(p string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	rel, err := filepath.Rel(r.base, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	if !r.anchored {
+		ok, _ := path.Match(r.pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against glob segments where ** spans
+// any number of segments
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// readIgnoreRules parses an ignore file whose patterns are relative to base
+func readIgnoreRules(base string, r io.Reader) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if rule, ok := newIgnoreRule(base, sc.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, sc.Err()
+}
+
+// loadIgnoreFile reads an ignore file, treating a missing file as empty
+func loadIgnoreFile(base, file string) ([]ignoreRule, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readIgnoreRules(base, f)
+}
+
+// excluded reports whether p is excluded by the rules. Later rules take
+// precedence over earlier ones, as in gitignore.
+func excluded(rules []ignoreRule, p string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.match(p, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
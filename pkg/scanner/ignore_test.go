@@ -0,0 +1,89 @@
+package scanner
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	base := filepath.FromSlash("/root")
+
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		// Unanchored patterns match the name at any depth
+		{"node_modules", "a/node_modules", true, true},
+		{"node_modules", "a/b/node_modules", true, true},
+		{"node_modules", "a/node_modules_old", true, false},
+		{"*.tmp", "a/b/c.tmp", false, true},
+		// Trailing slash only matches directories
+		{"build/", "a/build", true, true},
+		{"build/", "a/build", false, false},
+		// Leading slash anchors to the base
+		{"/data", "data", true, true},
+		{"/data", "a/data", true, false},
+		// Patterns with a slash are anchored as well
+		{"a/data", "a/data", true, true},
+		{"a/data", "b/a/data", true, false},
+		// ** spans any number of directories
+		{"**/data", "data", true, true},
+		{"**/data", "a/b/data", true, true},
+		{"a/**/data", "a/data", true, true},
+		{"a/**/data", "a/x/y/data", true, true},
+		{"a/**", "a/x/y", true, true},
+		{"a/**", "b/x", true, false},
+		// The base itself never matches
+		{"*", ".", true, false},
+	}
+
+	for _, tt := range tests {
+		rule, ok := newIgnoreRule(base, tt.pattern)
+		if !ok {
+			t.Fatalf("Failed to parse pattern %q", tt.pattern)
+		}
+		p := filepath.Join(base, filepath.FromSlash(tt.path))
+		if got := rule.match(p, tt.isDir); got != tt.want {
+			t.Errorf("%q matching %q (dir=%v): expected %v, got %v", tt.pattern, tt.path, tt.isDir, tt.want, got)
+		}
+	}
+}
+
+func TestReadIgnoreRules(t *testing.T) {
+	base := filepath.FromSlash("/root")
+	input := `# build output
+build/
+
+  
+!build/keep
+\!important
+`
+
+	rules, err := readIgnoreRules(base, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readIgnoreRules failed: %v", err)
+	}
+
+	// Test case 1: Comments and blank lines are skipped
+	if len(rules) != 3 {
+		t.Fatalf("Expected 3 rules, got %d", len(rules))
+	}
+
+	// Test case 2: Negated rules re-include paths
+	tests := map[string]bool{
+		"build":      true,
+		"a/build":    true,
+		"build/keep": false,
+		"!important": true,
+		"src":        false,
+	}
+	for path, want := range tests {
+		p := filepath.Join(base, filepath.FromSlash(path))
+		if got := excluded(rules, p, true); got != want {
+			t.Errorf("%s: expected excluded=%v, got %v", path, want, got)
+		}
+	}
+}
@@ -1,50 +1,263 @@
 package scanner
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/nguyendangminh/gus/pkg/git"
 )
 
 // Scanner represents a directory scanner
 type Scanner struct {
-	rootPath string
+	rootPath        string
+	excludes        []string
+	ignoreFile      string
+	defaultExcludes bool
+	maxDepth        int
+	followSymlinks  bool
+	oneFileSystem   bool
+
+	mu       sync.Mutex
+	warnings []Warning
+}
+
+// Option configures a Scanner
+type Option func(*Scanner)
+
+// WithExcludes skips directories matching the given gitignore-style
+// patterns, relative to the root directory
+func WithExcludes(patterns ...string) Option {
+	return func(s *Scanner) {
+		s.excludes = append(s.excludes, patterns...)
+	}
+}
+
+// WithIgnoreFile reads additional exclude patterns from a file, relative
+// to the root directory. A missing file is not an error.
+func WithIgnoreFile(path string) Option {
+	return func(s *Scanner) {
+		s.ignoreFile = path
+	}
+}
+
+// WithDefaultExcludes enables or disables the built-in DefaultExcludes
+func WithDefaultExcludes(enabled bool) Option {
+	return func(s *Scanner) {
+		s.defaultExcludes = enabled
+	}
+}
+
+// Warning records a path that could not be read during the scan
+type Warning struct {
+	Path string
+	Err  error
+}
+
+// Error implements the error interface
+func (w Warning) Error() string {
+	return fmt.Sprintf("%s: %v", w.Path, w.Err)
+}
+
+// Repo represents a Git repository found by the scanner
+type Repo struct {
+	Path   string
+	Kind   git.Kind
+	GitDir string
+	// Parent links worktrees to their main repository and submodules to
+	// their superproject
+	Parent string
+}
+
+// WithMaxDepth limits how many directory levels below the root are
+// descended into. Zero or a negative depth means no limit.
+func WithMaxDepth(depth int) Option {
+	return func(s *Scanner) {
+		s.maxDepth = depth
+	}
+}
+
+// WithFollowSymlinks makes the scanner descend into symbolic links to
+// directories. Directories reachable through several links, including
+// link loops, are only visited once.
+func WithFollowSymlinks(enabled bool) Option {
+	return func(s *Scanner) {
+		s.followSymlinks = enabled
+	}
+}
+
+// WithOneFileSystem keeps the scan on the file system of the root
+// directory, skipping mount points of other file systems
+func WithOneFileSystem(enabled bool) Option {
+	return func(s *Scanner) {
+		s.oneFileSystem = enabled
+	}
 }
 
 // New creates a new Scanner instance
-func New(rootPath string) *Scanner {
-	return &Scanner{
-		rootPath: rootPath,
+func New(rootPath string, opts ...Option) *Scanner {
+	s := &Scanner{
+		rootPath:        rootPath,
+		defaultExcludes: true,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Scan performs a recursive scan of the root directory
 func (s *Scanner) Scan() ([]string, error) {
 	var gitDirs []string
 
-	err := filepath.Walk(s.rootPath, func(path string, info os.FileInfo, err error) error {
+	err := s.Walk(context.Background(), func(repo Repo) error {
+		gitDirs = append(gitDirs, repo.Path)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return gitDirs, nil
+}
+
+// Warnings returns the paths that were skipped because they could not be
+// read during the last scan
+func (s *Scanner) Warnings() []Warning {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Warning(nil), s.warnings...)
+}
+
+// Walk performs a recursive scan of the root directory and calls fn for
+// every Git repository as soon as it is found. Unreadable paths below the
+// root are skipped and recorded as warnings. Directories matched by the
+// exclude patterns, the ignore file or a .gusignore file in one of their
+// parents are not descended into, and neither are directories beyond the
+// maximum depth or, if requested, on another file system. Linked worktrees
+// checked out inside their main repository are reported as well. The walk
+// stops when ctx is cancelled or fn returns an error, and that error is
+// returned.
+func (s *Scanner) Walk(ctx context.Context, fn func(Repo) error) error {
+	s.mu.Lock()
+	s.warnings = nil
+	s.mu.Unlock()
+
+	info, err := os.Stat(s.rootPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s: not a directory", s.rootPath)
+	}
+
+	w := &walker{
+		scanner:  s,
+		ctx:      ctx,
+		fn:       fn,
+		rules:    s.baseRules(),
+		dirRules: make(map[string][]ignoreRule),
+		visited:  make(map[string]bool),
+		reported: make(map[string]bool),
+	}
+	w.rootDev, w.hasRootDev = deviceID(info)
+
+	return w.walk(s.rootPath, info, 0)
+}
+
+// Stream performs the scan in the background and emits repositories on the
+// returned channel as they are found. The channel is closed when the walk
+// finishes; the error channel then receives the walk error, if any, and is
+// closed as well.
+func (s *Scanner) Stream(ctx context.Context) (<-chan Repo, <-chan error) {
+	repos := make(chan Repo)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		err := s.Walk(ctx, func(repo Repo) error {
+			select {
+			case repos <- repo:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(repos)
 		if err != nil {
-			return err
+			errc <- err
 		}
+	}()
+
+	return repos, errc
+}
 
-		// Skip if not a directory
-		if !info.IsDir() {
-			return nil
+// baseRules returns the rules that apply to the whole tree
+func (s *Scanner) baseRules() []ignoreRule {
+	var rules []ignoreRule
+	add := func(pattern string) {
+		if rule, ok := newIgnoreRule(s.rootPath, pattern); ok {
+			rules = append(rules, rule)
 		}
+	}
 
-		// Check if this is a Git repository
-		if git.IsGitRepo(path) {
-			gitDirs = append(gitDirs, path)
-			return filepath.SkipDir
+	if s.defaultExcludes {
+		for _, pattern := range DefaultExcludes {
+			add(pattern)
 		}
+	}
 
-		return nil
-	})
+	if s.ignoreFile != "" {
+		fileRules, err := loadIgnoreFile(s.rootPath, s.ignoreFile)
+		if err != nil {
+			s.warn(s.ignoreFile, err)
+		}
+		rules = append(rules, fileRules...)
+	}
 
-	if err != nil {
-		return nil, err
+	for _, pattern := range s.excludes {
+		add(pattern)
 	}
 
-	return gitDirs, nil
+	return rules
+}
+
+// rulesFor returns the base rules followed by the .gusignore rules of every
+// parent of path, outermost first
+func (s *Scanner) rulesFor(path string, base []ignoreRule, dirRules map[string][]ignoreRule) []ignoreRule {
+	var parents [][]ignoreRule
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if r, ok := dirRules[dir]; ok {
+			parents = append(parents, r)
+		}
+		if dir == s.rootPath || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	if len(parents) == 0 {
+		return base
+	}
+
+	rules := append([]ignoreRule(nil), base...)
+	for i := len(parents) - 1; i >= 0; i-- {
+		rules = append(rules, parents[i]...)
+	}
+	return rules
+}
+
+// warn records a skipped path
+func (s *Scanner) warn(path string, err error) {
+	// Walk errors already carry the path, keep only the cause
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+
+	s.mu.Lock()
+	s.warnings = append(s.warnings, Warning{Path: path, Err: err})
+	s.mu.Unlock()
 }
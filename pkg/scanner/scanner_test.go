@@ -1,9 +1,12 @@
 package scanner
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -70,3 +73,430 @@ func TestScan(t *testing.T) {
 		}
 	}
 }
+
+func TestStream(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "scanner-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize Git repositories
+	gitDirs := []string{
+		"a",
+		"b/c",
+		"d/e/f",
+	}
+
+	for _, dir := range gitDirs {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory %s: %v", dir, err)
+		}
+		cmd := exec.Command("git", "init")
+		cmd.Dir = filepath.Join(tempDir, dir)
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Failed to initialize Git repository in %s: %v", dir, err)
+		}
+	}
+
+	// Test case 1: All repositories are emitted in walk order
+	s := New(tempDir)
+	repos, errc := s.Stream(context.Background())
+
+	var found []string
+	for repo := range repos {
+		found = append(found, repo.Path)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	if len(found) != len(gitDirs) {
+		t.Fatalf("Expected %d Git repositories, found %d", len(gitDirs), len(found))
+	}
+	for i, dir := range gitDirs {
+		if expected := filepath.Join(tempDir, dir); found[i] != expected {
+			t.Errorf("Expected repository %d to be %s, got %s", i, expected, found[i])
+		}
+	}
+
+	// Test case 2: Cancelling the context stops the walk
+	ctx, cancel := context.WithCancel(context.Background())
+	repos, errc = s.Stream(ctx)
+	<-repos
+	cancel()
+	for range repos {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// Test case 3: Errors returned by the callback stop the walk
+	stop := errors.New("stop")
+	calls := 0
+	err = s.Walk(context.Background(), func(repo Repo) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected callback error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 callback, got %d", calls)
+	}
+}
+
+// runGit runs a Git command in dir with a fixed identity
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=gus", "-c", "user.email=gus@example.com", "-c", "protocol.file.allow=always"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestScanLinkedRepositories(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "scanner-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	tempDir, _ = filepath.EvalSymlinks(tempDir)
+
+	// Create a superproject with a submodule and a linked worktree
+	mainDir := filepath.Join(tempDir, "main")
+	libDir := filepath.Join(tempDir, "upstream", "lib")
+	for _, dir := range []string{mainDir, libDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		runGit(t, dir, "init")
+		runGit(t, dir, "commit", "--allow-empty", "-m", "initial")
+	}
+	runGit(t, mainDir, "submodule", "add", libDir, "lib")
+	runGit(t, mainDir, "commit", "-m", "add submodule")
+	runGit(t, mainDir, "worktree", "add", filepath.Join(tempDir, "wt", "feature"))
+
+	// Test scanning
+	expected := map[string]Repo{
+		mainDir:                                 {Kind: "main"},
+		filepath.Join(mainDir, "lib"):           {Kind: "submodule", Parent: mainDir},
+		filepath.Join(tempDir, "wt", "feature"): {Kind: "worktree", Parent: mainDir},
+		libDir:                                  {Kind: "main"},
+	}
+
+	found := make(map[string]Repo)
+	err = New(tempDir).Walk(context.Background(), func(repo Repo) error {
+		found[repo.Path] = repo
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+
+	if len(found) != len(expected) {
+		t.Errorf("Expected %d Git repositories, found %d: %v", len(expected), len(found), found)
+	}
+	for path, want := range expected {
+		got, ok := found[path]
+		if !ok {
+			t.Errorf("Expected to find Git repository in %s", path)
+			continue
+		}
+		if got.Kind != want.Kind || got.Parent != want.Parent {
+			t.Errorf("%s: expected %s of %q, got %s of %q", path, want.Kind, want.Parent, got.Kind, got.Parent)
+		}
+	}
+}
+
+func TestScanNestedWorktrees(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "scanner-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	tempDir, _ = filepath.EvalSymlinks(tempDir)
+
+	// Create a repository with worktrees inside and next to it
+	mainDir := filepath.Join(tempDir, "main")
+	if err := os.MkdirAll(mainDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	runGit(t, mainDir, "init")
+	runGit(t, mainDir, "commit", "--allow-empty", "-m", "initial")
+	nestedDir := filepath.Join(mainDir, ".worktrees", "feat")
+	siblingDir := filepath.Join(tempDir, "sibling")
+	runGit(t, mainDir, "worktree", "add", nestedDir)
+	runGit(t, mainDir, "worktree", "add", siblingDir)
+
+	walk := func(opts ...Option) map[string]int {
+		t.Helper()
+		found := make(map[string]int)
+		err := New(tempDir, opts...).Walk(context.Background(), func(repo Repo) error {
+			found[repo.Path]++
+			if repo.Path != mainDir && (repo.Kind != "worktree" || repo.Parent != mainDir) {
+				t.Errorf("%s: expected worktree of %s, got %s of %q", repo.Path, mainDir, repo.Kind, repo.Parent)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk failed: %v", err)
+		}
+		return found
+	}
+
+	// Test case 1: Nested and sibling worktrees are found once each
+	found := walk()
+	for _, path := range []string{mainDir, nestedDir, siblingDir} {
+		if found[path] != 1 {
+			t.Errorf("Expected to find %s once, found it %d times", path, found[path])
+		}
+	}
+	if len(found) != 3 {
+		t.Errorf("Expected 3 Git repositories, found %d: %v", len(found), found)
+	}
+
+	// Test case 2: A superproject is walked without reporting its nested
+	// worktree twice
+	if err := os.WriteFile(filepath.Join(mainDir, ".gitmodules"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create .gitmodules: %v", err)
+	}
+	if found := walk(); found[nestedDir] != 1 {
+		t.Errorf("Expected to find %s once, found it %d times", nestedDir, found[nestedDir])
+	}
+
+	// Test case 3: Exclude patterns and the maximum depth still apply
+	if found := walk(WithExcludes(".worktrees")); found[nestedDir] != 0 {
+		t.Errorf("Expected %s to be excluded", nestedDir)
+	}
+	if found := walk(WithMaxDepth(2)); found[nestedDir] != 0 || found[mainDir] != 1 {
+		t.Errorf("Expected %s to be beyond the maximum depth, found %v", nestedDir, found)
+	}
+}
+
+func TestScanUnreadableDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "scanner-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create a repository next to a directory that cannot be read
+	repoDir := filepath.Join(tempDir, "repo")
+	lockedDir := filepath.Join(tempDir, "locked")
+	for _, dir := range []string{repoDir, filepath.Join(lockedDir, "inner")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory %s: %v", dir, err)
+		}
+	}
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repoDir
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to initialize Git repository: %v", err)
+	}
+	if err := os.Chmod(lockedDir, 0); err != nil {
+		t.Fatalf("Failed to change permissions: %v", err)
+	}
+	defer os.Chmod(lockedDir, 0755)
+
+	// Test case 1: The scan continues past the unreadable directory
+	s := New(tempDir)
+	gitDirsFound, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(gitDirsFound) != 1 || gitDirsFound[0] != repoDir {
+		t.Errorf("Expected to find %s, got %v", repoDir, gitDirsFound)
+	}
+
+	// Test case 2: The unreadable directory is reported as a warning
+	warnings := s.Warnings()
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d", len(warnings))
+	}
+	if warnings[0].Path != lockedDir {
+		t.Errorf("Expected warning for %s, got %s", lockedDir, warnings[0].Path)
+	}
+	if !errors.Is(warnings[0].Err, os.ErrPermission) {
+		t.Errorf("Expected permission error, got %v", warnings[0].Err)
+	}
+
+	// Test case 3: An unreadable root is still fatal
+	if _, err := New(lockedDir).Scan(); err == nil {
+		t.Error("Expected error for unreadable root")
+	}
+}
+
+func TestScanExcludes(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "scanner-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize Git repositories, some of them in excluded directories
+	gitDirs := []string{
+		"app",
+		"app-data/repo",
+		"web/node_modules/dep",
+		"big/data/repo",
+		"big/keep/repo",
+		"other/data/repo",
+	}
+
+	for _, dir := range gitDirs {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory %s: %v", dir, err)
+		}
+		cmd := exec.Command("git", "init")
+		cmd.Dir = filepath.Join(tempDir, dir)
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Failed to initialize Git repository in %s: %v", dir, err)
+		}
+	}
+
+	// A .gusignore file only applies to its own subtree
+	if err := os.WriteFile(filepath.Join(tempDir, "big", IgnoreFileName), []byte("/*\n!/keep\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+
+	// A global ignore file applies to the whole tree
+	ignoreFile := filepath.Join(tempDir, "global-ignore")
+	if err := os.WriteFile(ignoreFile, []byte("# generated data\n/app-data\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+
+	scan := func(opts ...Option) []string {
+		t.Helper()
+		found, err := New(tempDir, opts...).Scan()
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		var rel []string
+		for _, dir := range found {
+			r, _ := filepath.Rel(tempDir, dir)
+			rel = append(rel, filepath.ToSlash(r))
+		}
+		return rel
+	}
+
+	check := func(name string, got, want []string) {
+		t.Helper()
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+
+	// Test case 1: Built-in defaults and .gusignore files
+	check("defaults", scan(), []string{"app", "app-data/repo", "big/keep/repo", "other/data/repo"})
+
+	// Test case 2: Defaults can be turned off
+	check("no defaults", scan(WithDefaultExcludes(false)),
+		[]string{"app", "app-data/repo", "big/keep/repo", "other/data/repo", "web/node_modules/dep"})
+
+	// Test case 3: Exclude patterns
+	check("excludes", scan(WithExcludes("data")), []string{"app", "app-data/repo", "big/keep/repo"})
+
+	// Test case 4: Global ignore file
+	check("ignore file", scan(WithIgnoreFile(ignoreFile)), []string{"app", "big/keep/repo", "other/data/repo"})
+
+	// Test case 5: A missing ignore file is not an error
+	s := New(tempDir, WithIgnoreFile(filepath.Join(tempDir, "missing")))
+	if _, err := s.Scan(); err != nil {
+		t.Errorf("Scan failed with missing ignore file: %v", err)
+	}
+	if len(s.Warnings()) != 0 {
+		t.Errorf("Expected no warnings, got %v", s.Warnings())
+	}
+}
+
+func TestScanTraversalOptions(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "scanner-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize Git repositories at different depths
+	gitDirs := []string{
+		"a",
+		"b/c",
+		"d/e/f",
+		"outside/g",
+	}
+
+	for _, dir := range gitDirs {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory %s: %v", dir, err)
+		}
+		cmd := exec.Command("git", "init")
+		cmd.Dir = filepath.Join(tempDir, dir)
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Failed to initialize Git repository in %s: %v", dir, err)
+		}
+	}
+
+	// Scan a root that links to a repository elsewhere and back to itself
+	root := filepath.Join(tempDir, "root")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, dir := range []string{"a", "b", "d"} {
+		if err := os.Rename(filepath.Join(tempDir, dir), filepath.Join(root, dir)); err != nil {
+			t.Fatalf("Failed to move directory %s: %v", dir, err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(tempDir, "outside"), filepath.Join(root, "link")); err != nil {
+		t.Skipf("Symbolic links are not supported: %v", err)
+	}
+	if err := os.Symlink(root, filepath.Join(root, "b", "loop")); err != nil {
+		t.Fatalf("Failed to create symbolic link: %v", err)
+	}
+
+	scan := func(opts ...Option) []string {
+		t.Helper()
+		found, err := New(root, opts...).Scan()
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		var rel []string
+		for _, dir := range found {
+			r, _ := filepath.Rel(root, dir)
+			rel = append(rel, filepath.ToSlash(r))
+		}
+		return rel
+	}
+
+	check := func(name string, got, want []string) {
+		t.Helper()
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+
+	// Test case 1: Symbolic links are not followed by default
+	check("default", scan(), []string{"a", "b/c", "d/e/f"})
+
+	// Test case 2: Maximum depth
+	check("max depth 1", scan(WithMaxDepth(1)), []string{"a"})
+	check("max depth 2", scan(WithMaxDepth(2)), []string{"a", "b/c"})
+
+	// Test case 3: Following symbolic links visits every directory once,
+	// even through the loop back to the root
+	check("follow symlinks", scan(WithFollowSymlinks(true)), []string{"a", "b/c", "d/e/f", "link/g"})
+
+	// Test case 4: Staying on one file system keeps everything on the same disk
+	check("one file system", scan(WithOneFileSystem(true)), []string{"a", "b/c", "d/e/f"})
+}
@@ -0,0 +1,206 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nguyendangminh/gus/pkg/git"
+)
+
+// walker holds the state of a single Walk
+type walker struct {
+	scanner *Scanner
+	ctx     context.Context
+	fn      func(Repo) error
+
+	// rules apply to the whole tree
+	rules []ignoreRule
+	// dirRules holds the .gusignore rules of directories seen so far
+	dirRules map[string][]ignoreRule
+	// visited holds the identity of directories already walked when
+	// following symbolic links
+	visited map[string]bool
+	// reported holds the working trees already passed to fn, as nested
+	// worktrees are reported before the walk reaches them
+	reported map[string]bool
+
+	rootDev    uint64
+	hasRootDev bool
+}
+
+// walk visits a directory at the given depth below the root and descends
+// into its subdirectories. Only fatal errors are returned.
+func (w *walker) walk(path string, info os.FileInfo, depth int) error {
+	s := w.scanner
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	if path != s.rootPath {
+		// Never descend into Git directories themselves
+		if info.Name() == ".git" {
+			return nil
+		}
+
+		if excluded(s.rulesFor(path, w.rules, w.dirRules), path, true) {
+			return nil
+		}
+
+		if s.oneFileSystem && w.hasRootDev {
+			if dev, ok := deviceID(info); ok && dev != w.rootDev {
+				return nil
+			}
+		}
+	}
+
+	if s.followSymlinks {
+		key := fileKey(path, info)
+		if w.visited[key] {
+			return nil
+		}
+		w.visited[key] = true
+	}
+
+	// Check if this is a Git repository
+	if gi, err := git.Resolve(path); err == nil {
+		if w.reported[path] {
+			return nil
+		}
+		w.reported[path] = true
+		repo := Repo{
+			Path:   path,
+			Kind:   gi.Kind,
+			GitDir: gi.GitDir,
+			Parent: gi.Parent,
+		}
+		if err := w.fn(repo); err != nil {
+			return err
+		}
+		if gi.Kind == git.KindMain {
+			if err := w.walkWorktrees(path, gi.GitDir, depth); err != nil {
+				return err
+			}
+		}
+		// Only superprojects can contain further repositories worth
+		// reporting, so skip the rest of the working tree otherwise
+		if !hasSubmodules(path) {
+			return nil
+		}
+	}
+
+	if s.maxDepth > 0 && depth >= s.maxDepth {
+		return nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if path == s.rootPath {
+			return err
+		}
+		s.warn(path, err)
+		return nil
+	}
+
+	for _, entry := range entries {
+		if entry.Name() != IgnoreFileName || entry.IsDir() {
+			continue
+		}
+		file := filepath.Join(path, IgnoreFileName)
+		if local, err := loadIgnoreFile(path, file); err != nil {
+			s.warn(file, err)
+		} else if len(local) > 0 {
+			w.dirRules[path] = local
+		}
+	}
+
+	for _, entry := range entries {
+		child := filepath.Join(path, entry.Name())
+
+		var childInfo os.FileInfo
+		switch {
+		case entry.IsDir():
+			childInfo, err = entry.Info()
+		case entry.Type()&os.ModeSymlink != 0 && s.followSymlinks:
+			childInfo, err = os.Stat(child)
+		default:
+			continue
+		}
+		if err != nil {
+			s.warn(child, err)
+			continue
+		}
+		if !childInfo.IsDir() {
+			continue
+		}
+
+		if err := w.walk(child, childInfo, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkWorktrees walks the linked worktrees of a main repository that are
+// checked out inside its own working tree, e.g. in .worktrees/<name>, which
+// the walk would otherwise not descend into. Worktrees elsewhere are left
+// to the walk itself.
+func (w *walker) walkWorktrees(path, gitDir string, depth int) error {
+	s := w.scanner
+	worktrees, err := git.Worktrees(gitDir)
+	if err != nil {
+		s.warn(filepath.Join(gitDir, "worktrees"), err)
+		return nil
+	}
+
+	base := realPath(path)
+	for _, worktree := range worktrees {
+		rel, err := filepath.Rel(base, realPath(worktree))
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		// Honour the exclude patterns and the maximum depth of every
+		// directory between the repository and the worktree
+		child, childDepth, skip := path, depth, false
+		for _, name := range strings.Split(rel, string(filepath.Separator)) {
+			child = filepath.Join(child, name)
+			childDepth++
+			if name == ".git" || excluded(s.rulesFor(child, w.rules, w.dirRules), child, true) {
+				skip = true
+				break
+			}
+		}
+		if skip || (s.maxDepth > 0 && childDepth > s.maxDepth) {
+			continue
+		}
+
+		info, err := os.Stat(child)
+		if err != nil {
+			s.warn(child, err)
+			continue
+		}
+		if err := w.walk(child, info, childDepth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// realPath resolves the symbolic links in a path, or returns it unchanged
+// if that fails
+func realPath(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return path
+}
+
+// hasSubmodules reports whether a working tree declares submodules
+func hasSubmodules(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".gitmodules"))
+	return err == nil
+}